@@ -1,13 +1,54 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
-	"exercise/internal/client"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"exercise/internal/certs"
+	"exercise/internal/generator"
+	"exercise/internal/random"
+	"exercise/internal/service"
+	"exercise/internal/state"
+	"fmt"
 	"math/big"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	v1 "exercise/pkg/ably/v1"
+	"exercise/pkg/client"
+)
+
+var (
+	ErrNotServing    = errors.New("server is not serving")
+	ErrNondetermined = errors.New("sequence cannot be regenerated")
+	ErrIntegrity     = errors.New("unsupported integrity mode")
+)
+
+const (
+	// MaxSeed upper limit for seeding service
+	MaxSeed = 0xff
+
+	// MaxQty upper limit for the number of values to be returned
+	MaxQty = 0xffff
+
+	// HealthTimeout how long to wait for a health check response
+	HealthTimeout = time.Duration(5) * time.Second
+
+	// DiscoveryTimeout how long to wait for the server to list its generators
+	DiscoveryTimeout = time.Duration(2) * time.Second
+
+	// ParamAnnotation marks the flags carrying a generator parameter, the flag name is used as the parameter name.
+	ParamAnnotation = "generator-param"
 )
 
 var (
@@ -41,8 +82,7 @@ var doublerCmd = &cobra.Command{
 An implementation as defined at https://gist.github.com/mattheworiordan/3f2f45ce1f6689c249c4195f38f1b6b7
 `,
 	Run: func(cmd *cobra.Command, _ []string) {
-		client := client.NewClient(cmd.Flags())
-		if err := client.Start("doubler"); err != nil {
+		if err := stream(cmd.Context(), cmd.Flags(), "doubler"); err != nil {
 			logger.Error().Err(err).Msg("An unhandled error occurred")
 		}
 	},
@@ -58,26 +98,368 @@ var randomCmd = &cobra.Command{
 An implementation as defined at https://gist.github.com/mattheworiordan/3f2f45ce1f6689c249c4195f38f1b6b7
 `,
 	Run: func(cmd *cobra.Command, _ []string) {
-		client := client.NewClient(cmd.Flags())
-		if err := client.Start("random"); err != nil {
+		if err := stream(cmd.Context(), cmd.Flags(), "random"); err != nil {
 			logger.Error().Err(err).Msg("An unhandled error occurred")
 		}
 	},
 }
 
+// replayCmd regenerates a deterministic sequence locally.
+var replayCmd = &cobra.Command{
+	Use:     "replay [generator]",
+	Example: "client replay random -n 10 --seed 42 --param mode=seeded --integrity chain",
+	Short:   "Regenerate a deterministic sequence offline",
+	Long: `Regenerate a deterministic sequence offline without connecting to the server
+
+Reports the checksum and the head of the SHA-256 chain over the values, which must match those
+streamed by the server for the same generator, seed, quantity and parameters.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		seed, err := cmd.Flags().GetInt64("seed")
+		if err != nil {
+			return err
+		}
+
+		qty, err := cmd.Flags().GetInt64("qty")
+		if err != nil {
+			return err
+		}
+
+		requested, err := cmd.Flags().GetStringToString("param")
+		if err != nil {
+			return err
+		}
+
+		g, err := service.DefaultRegistry().Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		params, err := generator.Validate(g, requested)
+		if err != nil {
+			return err
+		}
+		if !g.Deterministic(params) {
+			return fmt.Errorf("%w: %s with %v", ErrNondetermined, g.Name(), params)
+		}
+
+		iter, err := g.Iterator(big.NewInt(seed), params)
+		if err != nil {
+			return err
+		}
+
+		st := state.NewState(qty, &state.Source{Generator: g.Name(), Seed: big.NewInt(seed), Params: params})
+		st.Attach(iter)
+		for ok := st.Position() < qty; ok; ok = st.Next() {
+			logger.Debug().Int64("sequence", st.Position()).Str("value", st.Current().String()).Send()
+		}
+
+		logger.Info().
+			Str("checksum", st.Total().String()).
+			Str("chain", hex.EncodeToString(st.Chain())).
+			Msgf("Total: %d", st.Total())
+
+		return nil
+	},
+}
+
+// healthCmd queries the serving status of the server.
+var healthCmd = &cobra.Command{
+	Use:     "health",
+	Example: "client health -d localhost:9090 --service ably.v1.Service",
+	Short:   "Check the health of the server",
+	Long: `Check the health of the server using the standard grpc health checking protocol
+
+Exits with a non-zero status unless the server reports SERVING.
+`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		service, err := cmd.Flags().GetString("service")
+		if err != nil {
+			return err
+		}
+
+		c, err := newClient(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		defer func() { _ = c.Close() }()
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), HealthTimeout)
+		defer cancel()
+
+		status, err := c.Health(ctx, service)
+		if err != nil {
+			return err
+		}
+
+		logger.Info().Str("service", service).Msg(status.String())
+		if status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("%w: %s", ErrNotServing, status)
+		}
+
+		return nil
+	},
+}
+
+// tlsConfig creates the TLS configuration based on the supplied flags, TLS is used when enabled or any of the
+// certificate flags are supplied otherwise nil is returned and the connection is insecure.
+func tlsConfig(flags *pflag.FlagSet) (*tls.Config, error) {
+	enabled, _ := flags.GetBool("tls")
+	ca, _ := flags.GetString("tls-ca")
+	cert, _ := flags.GetString("tls-cert")
+	key, _ := flags.GetString("tls-key")
+	serverName, _ := flags.GetString("tls-server-name")
+
+	if !enabled && ca == "" && cert == "" && key == "" {
+		return nil, nil
+	}
+
+	return certs.ClientConfig(ca, cert, key, serverName)
+}
+
+// newClient creates a client of the server based on the supplied flags.
+func newClient(flags *pflag.FlagSet) (*client.Client, error) {
+	dsn, _ := flags.GetString("dsn")
+	transport, _ := flags.GetString("transport")
+	framing, _ := flags.GetString("ws-framing")
+	token, _ := flags.GetString("token")
+	tokenFile, _ := flags.GetString("token-file")
+
+	config, err := tlsConfig(flags)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure TLS: %w", err)
+	}
+
+	opts := []client.Option{
+		client.WithTransport(client.Transport(transport)),
+		client.WithFraming(client.Framing(framing)),
+		client.WithTLS(config),
+		client.WithToken(token),
+		client.WithTokenFile(tokenFile),
+		client.WithLogger(logger),
+	}
+	if stateless, err := flags.GetBool("stateless"); err == nil && stateless {
+		opts = append(opts, client.WithStateless())
+	}
+	if id, err := flags.GetString("client-id"); err == nil && id != "" {
+		opts = append(opts, client.WithClientID(id))
+	}
+
+	return client.New(dsn, opts...)
+}
+
+// buildRequest creates the request for the generator's sequence based on the supplied flags,
+// only parameters set explicitly are sent so the server applies the defaults for the rest.
+func buildRequest(flags *pflag.FlagSet, generator string) (client.Request, error) {
+	req := client.Request{Generator: generator, Params: map[string]string{}}
+	req.Qty, _ = flags.GetInt64("qty")
+	req.Interval, _ = flags.GetDuration("interval")
+	req.Subscribe, _ = flags.GetBool("subscribe")
+	req.Window, _ = flags.GetUint32("window")
+	req.StateFile, _ = flags.GetString("state-file")
+	if flags.Lookup("seed") != nil {
+		req.Seed, _ = flags.GetInt64("seed")
+	}
+	if flags.Lookup("last") != nil {
+		req.Last, _ = flags.GetInt64("last")
+	}
+
+	mode, _ := flags.GetString("integrity")
+	integrity, ok := v1.Integrity_value["INTEGRITY_"+strings.ToUpper(mode)]
+	if !ok {
+		return req, fmt.Errorf("%w: %s", ErrIntegrity, mode)
+	}
+	req.Integrity = v1.Integrity(integrity)
+
+	flags.Visit(func(f *pflag.Flag) {
+		if _, ok := f.Annotations[ParamAnnotation]; ok {
+			req.Params[f.Name] = f.Value.String()
+		}
+	})
+
+	return req, nil
+}
+
+// stream streams the generator's sequence requested by the supplied flags, logging its progress.
+func stream(ctx context.Context, flags *pflag.FlagSet, generator string) error {
+	req, err := buildRequest(flags, generator)
+	if err != nil {
+		return err
+	}
+
+	c, err := newClient(flags)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+	if id := c.ClientID(); id != "" {
+		logger.Debug().Msgf("Client ID: %s", id)
+	}
+
+	s, err := c.Stream(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for event := range s.Events() {
+		switch event.Type {
+		case client.Value:
+			logger.Debug().
+				Int64("sequence", event.Sequence).
+				Str("tally", event.Total.String()).
+				Str("value", event.Value.String()).
+				Send()
+		case client.Checksum:
+			logger.Debug().
+				Str("tally", event.Total.String()).
+				Str("checksum", event.Checksum.String()).
+				Send()
+		case client.Reconnecting:
+			if event.RetryAfter > 0 {
+				logger.Warn().Err(event.Err).Dur("retry-after", event.RetryAfter).Msg("Quota exceeded: waiting to resume")
+			} else {
+				logger.Debug().Timestamp().Err(event.Err).Msg("Stream lost: reconnecting")
+			}
+		case client.Resumed:
+			logger.Debug().Int64("sequence", event.Sequence).Msg("Stream resumed")
+		case client.Done:
+			if errors.Is(event.Err, context.Canceled) {
+				logger.Warn().Int64("received", event.Sequence).Msg("Interrupted")
+
+				continue
+			}
+			log := logger.Info()
+			if event.Chain != nil {
+				log = log.Str("chain", hex.EncodeToString(event.Chain))
+			}
+			log.Msgf("Total: %d (checksum=%t)", event.Total, event.Verified)
+		}
+	}
+
+	if err := s.Err(); !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
+
+// paramFlag adds a flag to cmd carrying the named parameter of the generator.
+func paramFlag(cmd *cobra.Command, gen, name, value, usage string) {
+	cmd.Flags().String(name, value, usage)
+	_ = cmd.Flags().SetAnnotation(name, ParamAnnotation, []string{gen})
+}
+
+// generatorCmd builds the command streaming a generator discovered from the server,
+// each of the generator's parameters is exposed as a flag.
+func generatorCmd(info *v1.GeneratorInfo) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     info.GetName(),
+		Example: fmt.Sprintf("client %s -d localhost:9090 -n 10", info.GetName()),
+		Short:   fmt.Sprintf("Run the client (%s)", info.GetName()),
+		Long:    fmt.Sprintf("Run the client (%s) to generate %s\n", info.GetName(), info.GetDescription()),
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := stream(cmd.Context(), cmd.Flags(), info.GetName()); err != nil {
+				logger.Error().Err(err).Msg("An unhandled error occurred")
+			}
+		},
+	}
+
+	cmd.Flags().Int64P("seed", "a", 0, "anything other than zero overrides the RNG for the seed value")
+	cmd.Flags().BoolP("stateless", "s", false, "run the grpc as stateless")
+	cmd.Flags().StringP("client-id", "c", "", "manually set the client-id to use")
+	for _, p := range info.GetParams() {
+		paramFlag(cmd, info.GetName(), p.GetName(), p.GetDefault(), p.GetDescription())
+	}
+
+	return cmd
+}
+
+// discover adds a command for each generator listed by the server which does not already have one.
+// Discovery is skipped when a known command is requested and silently abandoned should the server be unavailable.
+func discover(args []string) {
+	if cmd, _, err := rootCmd.Find(args); err == nil && cmd != rootCmd {
+		return
+	}
+
+	flags := pflag.NewFlagSet("discover", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.AddFlagSet(rootCmd.PersistentFlags())
+	if err := flags.Parse(args); err != nil && !errors.Is(err, pflag.ErrHelp) {
+		return
+	}
+	// generators are only listed over grpc
+	if transport, _ := flags.GetString("transport"); transport != string(client.GRPC) {
+		return
+	}
+
+	c, err := newClient(flags)
+	if err != nil {
+		logger.Debug().Err(err).Msg("Unable to discover generators")
+
+		return
+	}
+	defer func() { _ = c.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DiscoveryTimeout)
+	defer cancel()
+
+	generators, err := c.Generators(ctx)
+	if err != nil {
+		logger.Debug().Err(err).Msg("Unable to discover generators")
+
+		return
+	}
+
+	for _, info := range generators {
+		if cmd, _, err := rootCmd.Find([]string{info.GetName()}); err == nil && cmd != rootCmd {
+			continue
+		}
+		rootCmd.AddCommand(generatorCmd(info))
+	}
+}
+
 func main() {
-	cobra.CheckErr(rootCmd.Execute())
+	discover(os.Args[1:])
+
+	// interrupting the client closes any state file so the stream may be resumed from it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cobra.CheckErr(rootCmd.ExecuteContext(ctx))
 }
 
 func init() {
-	DefaultQty, _ = rand.Int(rand.Reader, big.NewInt(client.MaxQty))
-	DefaultSeed, _ = rand.Int(rand.Reader, big.NewInt(client.MaxSeed))
+	DefaultQty, _ = rand.Int(rand.Reader, big.NewInt(MaxQty))
+	DefaultSeed, _ = rand.Int(rand.Reader, big.NewInt(MaxSeed))
 	rootCmd.AddCommand(doublerCmd)
 	rootCmd.AddCommand(randomCmd)
+	rootCmd.AddCommand(healthCmd)
+	rootCmd.AddCommand(replayCmd)
 	rootCmd.PersistentFlags().StringP("dsn", "d", "localhost:9090", "the server and port that the grpc should connect to")
+	rootCmd.PersistentFlags().String("transport", string(client.GRPC), "stream over grpc or ws, a WebSocket to the server's HTTP gateway whose address --dsn must then be")
+	rootCmd.PersistentFlags().String("ws-framing", string(client.Binary), "how messages are framed over a WebSocket, binary as protobuf or json")
+	rootCmd.PersistentFlags().Bool("tls", false, "connect using TLS, verifying the server against the system roots unless --tls-ca is supplied")
+	rootCmd.PersistentFlags().String("tls-ca", "", "PEM encoded CA certificate used to verify the server, implies --tls")
+	rootCmd.PersistentFlags().String("tls-cert", "", "PEM encoded client certificate presented for mutual TLS, implies --tls")
+	rootCmd.PersistentFlags().String("tls-key", "", "PEM encoded key for the client certificate, implies --tls")
+	rootCmd.PersistentFlags().String("tls-server-name", "", "override the server name used to verify the server certificate")
+	rootCmd.PersistentFlags().String("token", "", "bearer token, a JWT or api key, sent with every call to authenticate the client")
+	rootCmd.PersistentFlags().String("token-file", "", "file holding the bearer token, read afresh for every call so it may be rotated")
+	rootCmd.PersistentFlags().String("state-file", "", "journal the values received to this file, a client restarted with it resumes the stream where it left off")
+	rootCmd.PersistentFlags().Duration("interval", 0, "request an interval between values, bounded by the server, zero accepts the server default")
+	rootCmd.PersistentFlags().Bool("subscribe", false, "stream bidirectionally acknowledging each value, unacknowledged values are resent on resume")
+	rootCmd.PersistentFlags().Uint32("window", 0, "values which may be unacknowledged when subscribed, zero accepts the server default")
+	rootCmd.PersistentFlags().String("integrity", "sum", "how values are verified, sum checks the total once complete, chain verifies each value against a SHA-256 chain")
 	rootCmd.PersistentFlags().Int64P("qty", "n", DefaultQty.Int64(), "override the RNG for how many values should be returned")
 	doublerCmd.Flags().Int64P("seed", "a", DefaultSeed.Int64(), "anything other than zero overrides the RNG for the seed value")
+	healthCmd.Flags().String("service", "", "the service to check, the overall server status if not set")
+	replayCmd.Flags().Int64P("seed", "a", 0, "the seed the sequence was generated from")
+	replayCmd.Flags().StringToString("param", nil, "generator parameters as name=value")
+	randomCmd.Flags().Int64P("seed", "a", 0, "the seed keying the sequence, required when seeded")
+	for _, p := range (random.Generator{}).Params() {
+		paramFlag(randomCmd, random.Name, p.Name, p.Default, p.Description)
+	}
 	randomCmd.Flags().BoolP("stateless", "s", false, "run the grpc as stateless")
-	randomCmd.Flags().Int64P("last", "l", 0, "the last value seen by the client")
+	randomCmd.Flags().Int64P("last", "l", 0, "the last value seen by the client, checked by the server against the value before the position resumed from by --state-file")
 	randomCmd.Flags().StringP("client-id", "c", "", "manually ser the client-id to use")
 }
@@ -1,21 +1,36 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
-	"exercise/internal/service"
+	"exercise/internal/auth"
+	"exercise/internal/certs"
+	"exercise/internal/gateway"
+	"exercise/internal/metrics"
+	"exercise/internal/pacing"
+	"exercise/internal/quota"
 	"fmt"
-	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/rs/cors"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/spf13/pflag"
 
 	v1 "exercise/pkg/ably/v1"
+	"exercise/pkg/server"
 )
 
+// EnvPrefix prefixes the environment variables flags may be set from.
+const EnvPrefix = "ABLY_"
+
 var (
 	ErrPortRequired = errors.New("you must provide a port number to start the server")
 	ErrPortNumber   = errors.New("the first arg must be a valid port number")
@@ -29,9 +44,118 @@ var rootCmd = &cobra.Command{
 	Use:     "server",
 	Example: "server 9090",
 	Short:   "Start the ably distributed exercise server",
-	Long:    `Start the ably distributed exercise server`,
-	Args:    validArgs,
-	Run:     runServer,
+	Long: `Start the ably distributed exercise server
+
+Every flag may also be set from the environment as ABLY_<FLAG>, e.g. ABLY_INTERVAL=250ms for --interval,
+flags supplied on the command line take precedence.
+`,
+	Args:              validArgs,
+	PersistentPreRunE: applyEnv,
+	Run:               runServer,
+}
+
+// applyEnv sets any flag not supplied on the command line from its ABLY_<FLAG> environment variable.
+func applyEnv(cmd *cobra.Command, _ []string) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || err != nil {
+			return
+		}
+
+		name := EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value for %s: %w", name, setErr)
+			}
+		}
+	})
+
+	return err
+}
+
+// buildPacing creates the pacing policy applied to streams based on the supplied flags.
+func buildPacing(flags *pflag.FlagSet) (pacing.Policy, error) {
+	mode, _ := flags.GetString("pacing")
+	interval, _ := flags.GetDuration("interval")
+	rate, _ := flags.GetFloat64("rate")
+	burst, _ := flags.GetInt("rate-burst")
+	jitter, _ := flags.GetFloat64("jitter")
+	minInterval, _ := flags.GetDuration("min-interval")
+	maxInterval, _ := flags.GetDuration("max-interval")
+
+	policy := pacing.Policy{
+		Mode:        pacing.Mode(mode),
+		Interval:    interval,
+		Rate:        rate,
+		Burst:       burst,
+		Jitter:      jitter,
+		MinInterval: minInterval,
+		MaxInterval: maxInterval,
+	}
+
+	return policy, policy.Validate()
+}
+
+// buildAuthenticator creates the authenticator requiring a bearer token of the sequence service based on the supplied
+// flags, nil when neither a key set nor api keys are supplied and the service is open to all.
+func buildAuthenticator(flags *pflag.FlagSet) (*auth.Authenticator, error) {
+	jwksPath, _ := flags.GetString("auth-jwks")
+	keysPath, _ := flags.GetString("auth-api-keys")
+	issuer, _ := flags.GetString("auth-issuer")
+	audience, _ := flags.GetString("auth-audience")
+	claim, _ := flags.GetString("auth-tenant-claim")
+	unexpiring, _ := flags.GetBool("auth-allow-unexpiring")
+
+	if jwksPath == "" && keysPath == "" {
+		return nil, nil
+	}
+
+	var jwt, apiKeys auth.Verifier
+	if jwksPath != "" {
+		var opts []auth.KeySetOption
+		if unexpiring {
+			opts = append(opts, auth.WithUnexpiringTokens())
+		}
+		ks, err := auth.LoadKeySet(jwksPath, issuer, audience, claim, opts...)
+		if err != nil {
+			return nil, err
+		}
+		jwt = ks
+	}
+	if keysPath != "" {
+		keys, err := auth.LoadAPIKeys(keysPath)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = keys
+	}
+
+	return auth.NewAuthenticator(jwt, apiKeys, []string{v1.Service_ServiceDesc.ServiceName}, auth.WithLogger(logger))
+}
+
+// buildQuotas creates the policy bounding the resources clients may use based on the supplied flags.
+func buildQuotas(flags *pflag.FlagSet) (quota.Policy, error) {
+	clientStreams, _ := flags.GetInt("client-streams")
+	clientValues, _ := flags.GetInt64("client-values-per-minute")
+	clientStates, _ := flags.GetInt("client-states")
+	streams, _ := flags.GetInt("max-streams")
+	values, _ := flags.GetInt64("max-values-per-minute")
+	states, _ := flags.GetInt("max-states")
+	stateBytes, _ := flags.GetInt64("max-state-bytes")
+	retryAfter, _ := flags.GetDuration("quota-retry-after")
+
+	policy := quota.Policy{
+		ClientStreams: clientStreams,
+		ClientValues:  clientValues,
+		ClientStates:  clientStates,
+		Streams:       streams,
+		Values:        values,
+		States:        states,
+		StateBytes:    stateBytes,
+		RetryAfter:    retryAfter,
+	}
+
+	return policy, policy.Validate()
 }
 
 // validArgs ensures that the first positional argument passed to the command is a valid port number.
@@ -47,39 +171,159 @@ func validArgs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// buildServer creates a configured instance of a grpc server.
-func buildServer() *grpc.Server {
-	var opts []grpc.ServerOption
+// buildTLSConfig creates the TLS configuration shared by the grpc and HTTP listeners based on the supplied flags,
+// TLS is used when a certificate is supplied and is reloaded from disk as it changes, nil when not enabled.
+func buildTLSConfig(flags *pflag.FlagSet) (*tls.Config, error) {
+	cert, _ := flags.GetString("tls-cert")
+	key, _ := flags.GetString("tls-key")
+	ca, _ := flags.GetString("tls-ca")
+	requireClientCert, _ := flags.GetBool("require-client-cert")
+
+	if cert == "" && key == "" && ca == "" && !requireClientCert {
+		return nil, nil
+	}
+
+	reloader, err := certs.NewReloader(cert, key, ca, requireClientCert, certs.WithLogger(logger))
+	if err != nil {
+		return nil, err
+	}
 
-	// todo: implement TLS
-	opts = append(opts, grpc.Creds(insecure.NewCredentials()))
-	srv := grpc.NewServer(opts...)
+	return reloader.Config(), nil
+}
 
-	service := service.NewService()
-	go service.MaintainStates() // todo: interim solution - needs better handling
+// buildCORS creates the policy allowing browsers on other origins to call the server based on the supplied flags,
+// nil when no origins are supplied and only pages served by the server itself may call it.
+func buildCORS(flags *pflag.FlagSet) *cors.Cors {
+	origins, _ := flags.GetStringSlice("cors-origins")
+	headers, _ := flags.GetStringSlice("cors-headers")
+	allowCredentials, _ := flags.GetBool("cors-credentials")
+	maxAge, _ := flags.GetDuration("cors-max-age")
 
-	v1.RegisterServiceServer(srv, service)
+	if len(origins) == 0 {
+		return nil
+	}
 
-	return srv
+	return cors.New(cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   append(gateway.CORSHeaders, headers...),
+		ExposedHeaders:   gateway.CORSExposedHeaders,
+		AllowCredentials: allowCredentials,
+		MaxAge:           int(maxAge.Seconds()),
+	})
 }
 
 // runServer starts a grpc server based upon the supplied arguments and flags.
+// The server stops accepting streams on SIGINT/SIGTERM and exits once active streams have finished.
 func runServer(cmd *cobra.Command, args []string) {
 	port, err := strconv.Atoi(args[0])
 	if err != nil {
 		logger.Fatal().Err(err)
 	}
 
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	grace, err := cmd.Flags().GetDuration("grace-period")
 	if err != nil {
-		logger.Fatal().Err(err)
+		logger.Fatal().Err(err).Send()
+	}
+
+	metricsAddr, err := cmd.Flags().GetString("metrics-addr")
+	if err != nil {
+		logger.Fatal().Err(err).Send()
 	}
 
-	srv := buildServer()
+	policy, err := buildPacing(cmd.Flags())
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
 
+	quotas, err := buildQuotas(cmd.Flags())
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	config, err := buildTLSConfig(cmd.Flags())
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Unable to configure TLS")
+	}
+
+	httpAddr, _ := cmd.Flags().GetString("http-addr")
+	stateFile, _ := cmd.Flags().GetString("state-file")
+	ttl, _ := cmd.Flags().GetDuration("state-ttl")
+	opts := []server.Option{
+		server.WithAddress(fmt.Sprintf(":%d", port)),
+		server.WithHTTPAddress(httpAddr),
+		server.WithTLS(config),
+		server.WithStateFile(stateFile),
+		server.WithStateTTL(ttl),
+		server.WithPacing(policy),
+		server.WithQuotas(quotas),
+		server.WithCORS(buildCORS(cmd.Flags())),
+		server.WithLogger(logger),
+	}
+	if enabled, _ := cmd.Flags().GetBool("reflection"); enabled {
+		opts = append(opts, server.WithReflection())
+	}
+
+	var m *metrics.Metrics
+	if metricsAddr != "" {
+		m = metrics.NewMetrics()
+		opts = append(opts, server.WithObserver(m), server.WithStreamInterceptors(m.StreamServerInterceptor()))
+	}
+
+	// streams are authenticated before quotas are applied as the tenant namespaces the client
+	authenticator, err := buildAuthenticator(cmd.Flags())
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Unable to configure authentication")
+	}
+	if authenticator != nil {
+		opts = append(opts,
+			server.WithStreamInterceptors(authenticator.StreamServerInterceptor()),
+			server.WithUnaryInterceptors(authenticator.UnaryServerInterceptor()),
+		)
+	}
+
+	srv, err := server.New(opts...)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := srv.Start(); err != nil {
+		logger.Fatal().Err(err).Msg("Unable to start server")
+	}
 	logger.Info().Msgf("Starting server on port %d", port)
-	if err := srv.Serve(listener); err != nil {
-		logger.Fatal().Err(err)
+
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	defer stopMetrics()
+	if m != nil {
+		go func() {
+			logger.Info().Msgf("Serving metrics on %s/metrics", metricsAddr)
+			if err := m.Serve(metricsCtx, metricsAddr); err != nil {
+				logger.Error().Err(err).Msg("Unable to serve metrics")
+			}
+		}()
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Wait() }()
+
+	shutdownCtx := context.Background()
+	select {
+	case err := <-served:
+		if err != nil {
+			logger.Error().Err(err).Msg("Server stopped unexpectedly")
+		}
+	case <-ctx.Done():
+		logger.Info().Dur("grace", grace).Msg("Shutting down")
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, grace)
+		defer cancel()
+	}
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error().Err(err).Send()
 	}
 	logger.Info().Msg("Stopped server")
 }
@@ -87,3 +331,41 @@ func runServer(cmd *cobra.Command, args []string) {
 func main() {
 	cobra.CheckErr(rootCmd.Execute())
 }
+
+func init() {
+	rootCmd.Flags().String("tls-cert", "", "PEM encoded server certificate, enables TLS and is reloaded when changed")
+	rootCmd.Flags().String("tls-key", "", "PEM encoded key for the server certificate")
+	rootCmd.Flags().String("tls-ca", "", "PEM encoded CA certificate used to verify client certificates")
+	rootCmd.Flags().Bool("require-client-cert", false, "require clients to present a certificate signed by --tls-ca (mutual TLS)")
+	rootCmd.Flags().String("state-file", "", "persist client states to this file so they survive a restart, held in memory if not set")
+	rootCmd.Flags().String("pacing", string(pacing.Fixed), fmt.Sprintf("how values are spaced out on a stream, one of %v", pacing.Modes))
+	rootCmd.Flags().Duration("interval", pacing.DefaultInterval, "period between values for the fixed and jitter pacing modes")
+	rootCmd.Flags().Float64("rate", 1, "values per second for the rate pacing mode")
+	rootCmd.Flags().Int("rate-burst", 1, "values which may be sent without delay for the rate pacing mode")
+	rootCmd.Flags().Float64("jitter", 0.2, "fraction, between 0 and 1, the interval varies by for the jitter pacing mode")
+	rootCmd.Flags().Duration("min-interval", 0, "the shortest interval a request may ask for")
+	rootCmd.Flags().Duration("max-interval", 0, "the longest interval a request may ask for, unbounded if zero")
+	rootCmd.Flags().Bool("reflection", false, "register the grpc reflection service so tools such as grpcurl can introspect the server")
+	rootCmd.Flags().String("metrics-addr", "", "address to expose prometheus metrics on at /metrics, e.g. :9100, disabled if not set")
+	rootCmd.Flags().String("http-addr", "", "address to serve the HTTP gateway on, e.g. :8080, streaming GET /v1/{generator} as Server-Sent Events and the rpcs over a WebSocket at /ws, disabled if not set")
+	rootCmd.Flags().StringSlice("cors-origins", nil, "origins browsers may call gRPC-Web, Connect and the HTTP gateway from, * allows any, only the server's own if not set")
+	rootCmd.Flags().StringSlice("cors-headers", nil, "request headers browsers may send in addition to those of the protocols served")
+	rootCmd.Flags().Bool("cors-credentials", false, "allow browsers to send cookies and credentials cross-origin, an origin of * then reflects the caller")
+	rootCmd.Flags().Duration("cors-max-age", 10*time.Minute, "how long browsers may cache the result of a preflight request")
+	rootCmd.Flags().Duration("grace-period", 10*time.Second, "how long active streams are given to complete on shutdown before being drained")
+	rootCmd.Flags().Duration("state-ttl", server.DefaultStateTTL, "how long client states are retained for after last being accessed")
+	rootCmd.Flags().String("auth-jwks", "", "JSON Web Key Set file verifying bearer JWTs, requires every call to the sequence service to be authenticated")
+	rootCmd.Flags().String("auth-api-keys", "", "file of \"<tenant> <key>\" lines accepted as bearer tokens, requires every call to the sequence service to be authenticated")
+	rootCmd.Flags().String("auth-issuer", "", "issuer JWTs must carry in their iss claim, unchecked if not set")
+	rootCmd.Flags().String("auth-audience", "", "audience JWTs must carry in their aud claim, unchecked if not set")
+	rootCmd.Flags().String("auth-tenant-claim", auth.DefaultTenantClaim, "claim of a JWT naming the tenant client states are namespaced by")
+	rootCmd.Flags().Bool("auth-allow-unexpiring", false, "accept JWTs without an exp claim, which are otherwise rejected as they would be valid forever")
+	rootCmd.Flags().Int("client-streams", 0, "streams each client may hold open at once, unbounded if zero")
+	rootCmd.Flags().Int64("client-values-per-minute", 0, "values each client may be sent per minute, unbounded if zero")
+	rootCmd.Flags().Int("client-states", 0, "states each client may retain at once under its client-ids, unbounded if zero")
+	rootCmd.Flags().Int("max-streams", 0, "streams which may be open at once across all clients, unbounded if zero")
+	rootCmd.Flags().Int64("max-values-per-minute", 0, "values which may be sent per minute across all clients, unbounded if zero")
+	rootCmd.Flags().Int("max-states", 0, "client states which may be retained at once, unbounded if zero")
+	rootCmd.Flags().Int64("max-state-bytes", 0, "memory the retained client states may hold, unbounded if zero")
+	rootCmd.Flags().Duration("quota-retry-after", quota.DefaultRetryAfter, "how long clients are asked to wait for a stream or state to be released once a limit is reached")
+}
@@ -0,0 +1,82 @@
+package arithmetic
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+)
+
+// Name the arithmetic generator is registered under.
+const Name = "arithmetic"
+
+// Generator produces the sequence of values starting at the seed where each is the last plus a step.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "a sequence of values starting at the seed where each value is the last plus the step"
+}
+
+// Params lists the parameters accepted.
+func (Generator) Params() []generator.Param {
+	return []generator.Param{
+		{Name: "step", Description: "the non-negative integer added to each value, of any size", Default: "1"},
+	}
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the values stepping from the seed.
+func (Generator) Iterator(seed *big.Int, params map[string]string) (generator.Iterator, error) {
+	step, err := generator.Int(params, "step")
+	if err != nil {
+		return nil, err
+	}
+	if step.Sign() < 0 {
+		return nil, generator.Invalid("step", "must not be negative, values are unsigned")
+	}
+
+	return NewIterator(seed, step), nil
+}
+
+// Iterator produces the arithmetic progression.
+type Iterator struct {
+	seed  *big.Int
+	step  *big.Int
+	value *big.Int
+}
+
+// Next returns the next value of the sequence.
+func (it *Iterator) Next() *big.Int {
+	value := new(big.Int).Set(it.value)
+	it.value.Add(it.value, it.step)
+
+	return value
+}
+
+// Reset returns the iterator to the seed.
+func (it *Iterator) Reset() {
+	it.value.Set(it.seed)
+}
+
+// SeekTo moves the iterator to position, the value at a position is the seed plus step*position.
+func (it *Iterator) SeekTo(position int64) {
+	it.value.Mul(it.step, big.NewInt(position))
+	it.value.Add(it.value, it.seed)
+}
+
+// NewIterator creates an iterator stepping from the seed.
+func NewIterator(seed, step *big.Int) *Iterator {
+	return &Iterator{
+		seed:  new(big.Int).Set(seed),
+		step:  new(big.Int).Set(step),
+		value: new(big.Int).Set(seed),
+	}
+}
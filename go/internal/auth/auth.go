@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Verifier establishes the tenant a bearer token was issued to.
+type Verifier interface {
+	// Verify returns the tenant of the token, failing if the token is not one the verifier accepts.
+	Verify(token string) (string, error)
+}
+
+// tenantKey is the context key the authenticated tenant is held against.
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the authenticated tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant the request was authenticated as, if any.
+func Tenant(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+
+	return tenant, ok && tenant != ""
+}
+
+// Authenticator requires the rpcs of the services it protects to carry a bearer token accepted by one of its verifiers.
+// JWTs are verified against the key set, any other token is looked up amongst the api keys.
+type Authenticator struct {
+	jwt      Verifier
+	apiKeys  Verifier
+	services []string
+	logger   zerolog.Logger
+}
+
+// protects reports whether the full method name is of one of the services requiring authentication.
+func (a *Authenticator) protects(method string) bool {
+	for _, service := range a.services {
+		if strings.HasPrefix(method, "/"+service+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bearer extracts the bearer token from the request metadata.
+func bearer(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(MetadataKey)
+	if len(values) == 0 {
+		return "", ErrMissing
+	}
+
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], Scheme) || strings.TrimSpace(parts[1]) == "" {
+		return "", fmt.Errorf("%w: expected %s scheme", ErrInvalid, Scheme)
+	}
+
+	return strings.TrimSpace(parts[1]), nil
+}
+
+// Authenticate verifies the bearer token carried by the request, returning a context holding its tenant.
+func (a *Authenticator) Authenticate(ctx context.Context) (context.Context, error) {
+	token, err := bearer(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	// a JWT is three dot separated segments whereas api keys are opaque
+	verifier := a.apiKeys
+	if strings.Count(token, ".") == 2 {
+		verifier = a.jwt
+	}
+	if verifier == nil {
+		return nil, status.Error(codes.Unauthenticated, ErrInvalid.Error())
+	}
+
+	tenant, err := verifier.Verify(token)
+	if err != nil {
+		a.logger.Debug().Err(err).Msg("Rejected bearer token")
+
+		return nil, status.Error(codes.Unauthenticated, ErrInvalid.Error())
+	}
+
+	return WithTenant(ctx, tenant), nil
+}
+
+// StreamServerInterceptor rejects streams of the protected services which are not authenticated,
+// the stream's context carries the tenant of those which are.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !a.protects(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := a.Authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		wrapped := grpcMiddleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+
+		return handler(srv, wrapped)
+	}
+}
+
+// UnaryServerInterceptor rejects calls to the protected services which are not authenticated.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !a.protects(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := a.Authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewAuthenticator requires the named services to be called with a token accepted by the JWT or api key verifier,
+// either of which may be nil though not both.
+func NewAuthenticator(jwt, apiKeys Verifier, services []string, opts ...Option) (*Authenticator, error) {
+	if jwt == nil && apiKeys == nil {
+		return nil, ErrNoVerifier
+	}
+
+	a := &Authenticator{jwt: jwt, apiKeys: apiKeys, services: services, logger: logger}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a, nil
+}
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// keys are the private keys of the signing keys in the set written by keySet.
+type keys struct {
+	rsa     *rsa.PrivateKey
+	ec      *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+// b64 encodes a member of a JWK.
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// keySet writes a JSON Web Key Set holding an RSA, EC and Ed25519 key, and an encryption key which is ignored.
+func keySet(t *testing.T) (string, keys) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := map[string][]jwk{"keys": {
+		{Kty: "RSA", Kid: "rsa", Use: "sig", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: b64(ecKey.X.Bytes()), Y: b64(ecKey.Y.Bytes())},
+		{Kty: "OKP", Kid: "ed25519", Crv: "Ed25519", X: b64(edPublic)},
+		{Kty: "RSA", Kid: "enc", Use: "enc", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, keys{rsa: rsaKey, ec: ecKey, ed25519: edKey}
+}
+
+// sign signs the claims with the key, naming it by kid in the header unless empty.
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw
+}
+
+// claims are those of a token issued to acme which expires in an hour, with any overrides applied.
+func claims(overrides jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{"sub": "acme", "iss": "issuer", "aud": "audience", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range overrides {
+		if value == nil {
+			delete(c, name)
+		} else {
+			c[name] = value
+		}
+	}
+
+	return c
+}
+
+func TestKeySet(t *testing.T) {
+	t.Parallel()
+	path, k := keySet(t)
+	ks, err := LoadKeySet(path, "issuer", "audience", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ks.keys["enc"]; ok {
+		t.Fatal("encryption key loaded")
+	}
+
+	past := time.Now().Add(-time.Hour).Unix()
+	tests := []struct {
+		name  string
+		token string
+		// tenant the token is accepted for, rejected if empty.
+		tenant string
+	}{
+		{name: "rsa", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(nil)), tenant: "acme"},
+		{name: "rsa pss", token: sign(t, jwt.SigningMethodPS256, k.rsa, "rsa", claims(nil)), tenant: "acme"},
+		{name: "ec", token: sign(t, jwt.SigningMethodES256, k.ec, "ec", claims(nil)), tenant: "acme"},
+		{name: "ed25519", token: sign(t, jwt.SigningMethodEdDSA, k.ed25519, "ed25519", claims(nil)), tenant: "acme"},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, k.rsa, "other", claims(nil))},
+		{name: "no kid", token: sign(t, jwt.SigningMethodRS256, k.rsa, "", claims(nil))},
+		{name: "encryption key", token: sign(t, jwt.SigningMethodRS256, k.rsa, "enc", claims(nil))},
+		{name: "wrong key", token: sign(t, jwt.SigningMethodES256, k.ec, "rsa", claims(nil))},
+		{name: "hmac with public key", token: sign(t, jwt.SigningMethodHS256, []byte(b64(k.rsa.N.Bytes())), "rsa", claims(nil))},
+		{name: "none", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa", claims(nil))},
+		{name: "expired", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(jwt.MapClaims{"exp": past}))},
+		{name: "unexpiring", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(jwt.MapClaims{"exp": nil}))},
+		{name: "not yet valid", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()}))},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(jwt.MapClaims{"iss": "other"}))},
+		{name: "wrong audience", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(jwt.MapClaims{"aud": "other"}))},
+		{name: "no tenant", token: sign(t, jwt.SigningMethodRS256, k.rsa, "rsa", claims(jwt.MapClaims{"sub": nil}))},
+		{name: "malformed", token: "not.a.jwt"},
+	}
+	for _, tt := range tests {
+		tenant, err := ks.Verify(tt.token)
+		switch {
+		case tt.tenant != "" && (err != nil || tenant != tt.tenant):
+			t.Errorf("%s: verified as %q: %v", tt.name, tenant, err)
+		case tt.tenant == "" && !errors.Is(err, ErrInvalid):
+			t.Errorf("%s: expected %s, got tenant %q: %v", tt.name, ErrInvalid, tenant, err)
+		}
+	}
+}
+
+func TestKeySetOptions(t *testing.T) {
+	t.Parallel()
+	path, k := keySet(t)
+
+	// tokens without an expiry are only accepted when explicitly allowed
+	unexpiring := sign(t, jwt.SigningMethodES256, k.ec, "ec", claims(jwt.MapClaims{"exp": nil, "tenant": "globex"}))
+	ks, err := LoadKeySet(path, "", "", "tenant", WithUnexpiringTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant, err := ks.Verify(unexpiring); err != nil || tenant != "globex" {
+		t.Fatalf("unexpiring token verified as %q: %v", tenant, err)
+	}
+	expired := sign(t, jwt.SigningMethodES256, k.ec, "ec", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix(), "tenant": "globex"}))
+	if _, err := ks.Verify(expired); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expired token accepted: %v", err)
+	}
+
+	// a set of one key verifies tokens not naming it
+	single := filepath.Join(t.TempDir(), "single.json")
+	data, _ := json.Marshal(map[string][]jwk{"keys": {{Kty: "EC", Crv: "P-256", X: b64(k.ec.X.Bytes()), Y: b64(k.ec.Y.Bytes())}}})
+	if err := os.WriteFile(single, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	ks, err = LoadKeySet(single, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant, err := ks.Verify(sign(t, jwt.SigningMethodES256, k.ec, "", claims(nil))); err != nil || tenant != "acme" {
+		t.Fatalf("token without kid verified as %q: %v", tenant, err)
+	}
+}
+
+func TestLoadKeySetRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"malformed":     `{"keys": [`,
+		"empty":         `{"keys": []}`,
+		"only enc":      `{"keys": [{"kty": "OKP", "crv": "Ed25519", "use": "enc", "x": "` + b64(make([]byte, 32)) + `"}]}`,
+		"unknown type":  `{"keys": [{"kty": "oct", "k": "c2VjcmV0"}]}`,
+		"unknown curve": `{"keys": [{"kty": "EC", "crv": "P-192", "x": "AQ", "y": "AQ"}]}`,
+		"off curve":     `{"keys": [{"kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`,
+		"short ed25519": `{"keys": [{"kty": "OKP", "crv": "Ed25519", "x": "AQ"}]}`,
+		"missing n":     `{"keys": [{"kty": "RSA", "e": "AQAB"}]}`,
+	}
+	for name, set := range tests {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, []byte(set), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKeySet(path, "", "", ""); !errors.Is(err, ErrKeySet) {
+			t.Errorf("%s: expected %s, got %v", name, ErrKeySet, err)
+		}
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "keys")
+	contents := "# tenants\nacme key-one\n\nacme key-two\n  globex   key-three  \n"
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{"key-one": "acme", "key-two": "acme", "key-three": "globex", "key-four": "", "acme": ""}
+	for key, want := range tests {
+		tenant, err := a.Verify(key)
+		switch {
+		case want != "" && (err != nil || tenant != want):
+			t.Errorf("%s: verified as %q want %q: %v", key, tenant, want, err)
+		case want == "" && !errors.Is(err, ErrInvalid):
+			t.Errorf("%s: expected %s, got tenant %q: %v", key, ErrInvalid, tenant, err)
+		}
+	}
+
+	for name, contents := range map[string]string{"one field": "acme\n", "three fields": "acme key extra\n", "reused": "acme key\nglobex key\n"} {
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAPIKeys(path); !errors.Is(err, ErrAPIKeys) {
+			t.Errorf("%s: expected %s, got %v", name, ErrAPIKeys, err)
+		}
+	}
+}
+
+// verifier accepts a single token for a tenant.
+type verifier struct {
+	token, tenant string
+}
+
+func (v verifier) Verify(token string) (string, error) {
+	if token != v.token {
+		return "", ErrInvalid
+	}
+
+	return v.tenant, nil
+}
+
+// bearing returns a context whose metadata carries the authorization.
+func bearing(authorization string) context.Context {
+	if authorization == "" {
+		return context.Background()
+	}
+
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, authorization))
+}
+
+func TestAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewAuthenticator(nil, nil, nil); !errors.Is(err, ErrNoVerifier) {
+		t.Fatalf("expected %s, got %v", ErrNoVerifier, err)
+	}
+
+	jwtToken := "header.payload.signature"
+	a, err := NewAuthenticator(verifier{token: jwtToken, tenant: "acme"}, verifier{token: "api-key", tenant: "globex"}, []string{"protected.Service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		ctx    context.Context
+		// tenant the call is authenticated as, empty if not authenticated.
+		tenant string
+		code   codes.Code
+	}{
+		{name: "jwt", method: "/protected.Service/Call", ctx: bearing("Bearer " + jwtToken), tenant: "acme"},
+		{name: "api key", method: "/protected.Service/Call", ctx: bearing("bearer api-key"), tenant: "globex"},
+		{name: "unknown jwt", method: "/protected.Service/Call", ctx: bearing("Bearer other.payload.signature"), code: codes.Unauthenticated},
+		{name: "unknown api key", method: "/protected.Service/Call", ctx: bearing("Bearer other"), code: codes.Unauthenticated},
+		{name: "other scheme", method: "/protected.Service/Call", ctx: bearing("Basic api-key"), code: codes.Unauthenticated},
+		{name: "empty", method: "/protected.Service/Call", ctx: bearing("Bearer "), code: codes.Unauthenticated},
+		{name: "missing", method: "/protected.Service/Call", ctx: bearing(""), code: codes.Unauthenticated},
+		{name: "unprotected", method: "/grpc.health.v1.Health/Check", ctx: bearing("")},
+		{name: "service prefix", method: "/protected.ServiceAdmin/Call", ctx: bearing("")},
+	}
+	for _, tt := range tests {
+		var tenant string
+		_, err := a.UnaryServerInterceptor()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			tenant, _ = Tenant(ctx)
+
+			return nil, nil
+		})
+		if status.Code(err) != tt.code || tenant != tt.tenant {
+			t.Errorf("%s: authenticated as %q with %s, want %q with %s", tt.name, tenant, status.Code(err), tt.tenant, tt.code)
+		}
+	}
+}
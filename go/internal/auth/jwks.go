@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// jwk is a single JSON Web Key as held in a key set, only the members describing public keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// curves maps the curves of EC keys to their implementation.
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// decode decodes an unpadded base64url member of a key.
+func decode(member, value string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("%w: malformed %s", ErrKeySet, member)
+	}
+
+	return b, nil
+}
+
+// public builds the public key the JWK describes.
+func (k jwk) public() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decode("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrKeySet, k.Crv)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point is not on %s", ErrKeySet, k.Crv)
+		}
+
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrKeySet, k.Crv)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: malformed x", ErrKeySet)
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrKeySet, k.Kty)
+	}
+}
+
+// KeySet verifies JWTs signed by one of the keys of a JSON Web Key Set, the tenant is taken from a claim of the token.
+type KeySet struct {
+	keys map[string]interface{}
+	// issuer and audience the token must have been issued by and for, unchecked if empty.
+	issuer   string
+	audience string
+	// claim naming the tenant.
+	claim string
+	// unexpiring accepts tokens without an exp claim, which would otherwise be valid forever.
+	unexpiring bool
+}
+
+// KeySetOption configures a KeySet.
+type KeySetOption func(*KeySet)
+
+// WithUnexpiringTokens accepts tokens without an exp claim, by default a token must expire.
+func WithUnexpiringTokens() KeySetOption {
+	return func(ks *KeySet) {
+		ks.unexpiring = true
+	}
+}
+
+// key finds the key a token was signed with by its key id, a token without one may only be verified by a set of one key.
+// The key must be of the type the token's signing method uses so an attacker cannot choose how it is interpreted.
+func (ks *KeySet) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok && kid == "" && len(ks.keys) == 1 {
+		for _, only := range ks.keys {
+			key, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalid, kid)
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok = key.(*rsa.PublicKey)
+	case *jwt.SigningMethodECDSA:
+		_, ok = key.(*ecdsa.PublicKey)
+	case *jwt.SigningMethodEd25519:
+		_, ok = key.(ed25519.PublicKey)
+	default:
+		ok = false
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: key %q cannot verify %s", ErrInvalid, kid, token.Method.Alg())
+	}
+
+	return key, nil
+}
+
+// Verify returns the tenant named by a JWT signed by one of the keys, which has not expired and was issued
+// by and for the configured issuer and audience.
+func (ks *KeySet) Verify(raw string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(raw, claims, ks.key); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	// the parser only checks the expiry of tokens which have one
+	if !ks.unexpiring && !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return "", fmt.Errorf("%w: no exp claim", ErrInvalid)
+	}
+
+	if ks.issuer != "" && !claims.VerifyIssuer(ks.issuer, true) {
+		return "", fmt.Errorf("%w: issuer not accepted", ErrInvalid)
+	}
+	if ks.audience != "" && !claims.VerifyAudience(ks.audience, true) {
+		return "", fmt.Errorf("%w: audience not accepted", ErrInvalid)
+	}
+
+	tenant, _ := claims[ks.claim].(string)
+	if tenant == "" {
+		return "", fmt.Errorf("%w: no %s claim", ErrInvalid, ks.claim)
+	}
+
+	return tenant, nil
+}
+
+// LoadKeySet reads the JSON Web Key Set at path, tokens must name their tenant in the claim and,
+// where not empty, be issued by issuer for audience. Keys not intended for signatures are ignored.
+func LoadKeySet(path, issuer, audience, claim string, opts ...KeySetOption) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeySet, err)
+	}
+
+	ks := &KeySet{keys: map[string]interface{}{}, issuer: issuer, audience: audience, claim: claim}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.public()
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[k.Kid] = key
+	}
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("%w: no signing keys in %s", ErrKeySet, path)
+	}
+	if ks.claim == "" {
+		ks.claim = DefaultTenantClaim
+	}
+	for _, opt := range opts {
+		opt(ks)
+	}
+
+	return ks, nil
+}
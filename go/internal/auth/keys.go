@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKeys verifies static api keys, each issued to a tenant. Only the SHA-256 digest of each key is held.
+type APIKeys struct {
+	tenants map[[sha256.Size]byte]string
+}
+
+// Verify returns the tenant the key was issued to.
+func (a *APIKeys) Verify(key string) (string, error) {
+	if tenant, ok := a.tenants[sha256.Sum256([]byte(key))]; ok {
+		return tenant, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown api key", ErrInvalid)
+}
+
+// LoadAPIKeys reads the api keys file at path, each line holds a tenant and a key issued to it separated by
+// whitespace. Blank lines and those starting with # are ignored, a tenant may hold several keys.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	a := &APIKeys{tenants: map[[sha256.Size]byte]string{}}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: line %d must hold a tenant and a key", ErrAPIKeys, line)
+		}
+		digest := sha256.Sum256([]byte(fields[1]))
+		if tenant, ok := a.tenants[digest]; ok && tenant != fields[0] {
+			return nil, fmt.Errorf("%w: line %d reuses the key of tenant %s", ErrAPIKeys, line, tenant)
+		}
+		a.tenants[digest] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
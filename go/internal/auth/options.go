@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"github.com/rs/zerolog"
+)
+
+// Option configures an Authenticator.
+type Option func(*Authenticator)
+
+// WithLogger sets the logger rejected tokens are reported to, stderr by default.
+func WithLogger(l zerolog.Logger) Option {
+	return func(a *Authenticator) {
+		a.logger = l
+	}
+}
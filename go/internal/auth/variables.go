@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"errors"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// MetadataKey is the request metadata carrying the credentials.
+	MetadataKey = "authorization"
+
+	// Scheme prefixes the credentials within the metadata.
+	Scheme = "Bearer"
+
+	// DefaultTenantClaim is the claim of a JWT naming the tenant when not otherwise configured.
+	DefaultTenantClaim = "sub"
+)
+
+var (
+	ErrMissing    = errors.New("no bearer token supplied")
+	ErrInvalid    = errors.New("invalid bearer token")
+	ErrKeySet     = errors.New("invalid key set")
+	ErrAPIKeys    = errors.New("invalid api keys file")
+	ErrNoVerifier = errors.New("neither a key set nor api keys configured")
+)
+
+// logger represents a configured instance of zerolog.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Logger().Output(zerolog.ConsoleWriter{Out: os.Stderr})
@@ -0,0 +1,219 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Reloader serves a server certificate and client CA pool from disk,
+// reloading them whenever the underlying files change so certificates can be rotated without a restart.
+type Reloader struct {
+	certFile, keyFile, caFile string
+	requireClientCert         bool
+
+	mu      sync.Mutex
+	checked time.Time
+	modTime time.Time
+	config  *tls.Config
+	logger  zerolog.Logger
+}
+
+// modified returns the most recent modification time of the configured files.
+func (r *Reloader) modified() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// load reads the configured files building the tls.Config presented to clients.
+func (r *Reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+	}
+
+	if r.caFile != "" {
+		pool, err := Pool(r.caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	if r.requireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// current returns the active tls.Config, reloading it if the files have changed since last checked.
+// A failed reload is logged and the previous configuration retained.
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) < ReloadInterval {
+		return r.config
+	}
+	r.checked = time.Now()
+
+	modTime, err := r.modified()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Unable to check certificates for changes")
+
+		return r.config
+	}
+	if !modTime.After(r.modTime) {
+		return r.config
+	}
+
+	config, err := r.load()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Unable to reload certificates")
+
+		return r.config
+	}
+	r.config = config
+	r.modTime = modTime
+	r.logger.Info().Msg("Reloaded certificates")
+
+	return r.config
+}
+
+// Config returns a tls.Config which resolves the current certificates on each handshake.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// NewReloader loads the server certificate and key, with an optional CA used to verify client certificates.
+func NewReloader(certFile, keyFile, caFile string, requireClientCert bool, opts ...Option) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, ErrKeyPair
+	}
+	if requireClientCert && caFile == "" {
+		return nil, ErrCARequired
+	}
+
+	r := &Reloader{
+		certFile:          certFile,
+		keyFile:           keyFile,
+		caFile:            caFile,
+		requireClientCert: requireClientCert,
+		checked:           time.Now(),
+		logger:            logger,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	modTime, err := r.modified()
+	if err != nil {
+		return nil, err
+	}
+	if r.config, err = r.load(); err != nil {
+		return nil, err
+	}
+	r.modTime = modTime
+
+	return r, nil
+}
+
+// Pool reads the PEM encoded certificates in file into a new pool.
+func Pool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w in %s", ErrNoCertificate, file)
+	}
+
+	return pool, nil
+}
+
+// ClientConfig builds the tls.Config for a client, verifying the server against caFile or the system roots
+// if not supplied and presenting the certificate in certFile and keyFile when supplied.
+func ClientConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caFile != "" {
+		pool, err := Pool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, ErrKeyPair
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Identity returns the subject of the verified client certificate presented on the connection, if any.
+// The common name is used where present, otherwise the full distinguished name.
+func Identity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	subject := info.State.VerifiedChains[0][0].Subject
+	if subject.CommonName != "" {
+		return subject.CommonName, true
+	}
+
+	return subject.String(), true
+}
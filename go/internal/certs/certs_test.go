@@ -0,0 +1,189 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// write creates a self-signed certificate for 127.0.0.1 with the common name, written to cert.pem and key.pem in dir
+// as if modified at the time given.
+func write(t *testing.T, dir, commonName string, modified time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	for file, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return certFile, keyFile
+}
+
+// served returns the common name of the certificate the reloader presents to a client connecting.
+func served(t *testing.T, r *Reloader) string {
+	t.Helper()
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", r.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		conn, err := lis.Accept()
+		if err == nil {
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// the certificate is only inspected, each rotation being signed by a different key
+	conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+// due makes the reloader check the files for changes on the next handshake rather than waiting for ReloadInterval.
+func due(r *Reloader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checked = time.Time{}
+}
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := write(t, dir, "first", start)
+
+	var logs bytes.Buffer
+	r, err := NewReloader(certFile, keyFile, "", false, WithLogger(zerolog.New(&logs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := served(t, r); name != "first" {
+		t.Fatalf("served %s", name)
+	}
+
+	// rotated certificates are only picked up once the reload interval has passed
+	write(t, dir, "second", start.Add(time.Minute))
+	if name := served(t, r); name != "first" {
+		t.Fatalf("served %s within the reload interval", name)
+	}
+	due(r)
+	if name := served(t, r); name != "second" || !strings.Contains(logs.String(), "Reloaded certificates") {
+		t.Fatalf("served %s once rotated, logging %s", name, logs.String())
+	}
+
+	// a certificate which fails to load, or is missing, leaves the previous one served
+	if err := os.WriteFile(certFile, []byte("corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := start.Add(2 * time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	due(r)
+	if name := served(t, r); name != "second" || !strings.Contains(logs.String(), "Unable to reload certificates") {
+		t.Fatalf("served %s once corrupted, logging %s", name, logs.String())
+	}
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	due(r)
+	if name := served(t, r); name != "second" || !strings.Contains(logs.String(), "Unable to check certificates") {
+		t.Fatalf("served %s once removed, logging %s", name, logs.String())
+	}
+}
+
+func TestReloaderClientAuth(t *testing.T) {
+	t.Parallel()
+	certFile, keyFile := write(t, t.TempDir(), "server", time.Now())
+
+	tests := []struct {
+		caFile  string
+		require bool
+		auth    tls.ClientAuthType
+		err     error
+	}{
+		{auth: tls.NoClientCert},
+		{caFile: certFile, auth: tls.VerifyClientCertIfGiven},
+		{caFile: certFile, require: true, auth: tls.RequireAndVerifyClientCert},
+		{require: true, err: ErrCARequired},
+		{caFile: keyFile, err: ErrNoCertificate},
+	}
+	for _, tt := range tests {
+		r, err := NewReloader(certFile, keyFile, tt.caFile, tt.require)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ca %q required %t: expected %s, got %v", tt.caFile, tt.require, tt.err, err)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth := r.current().ClientAuth; auth != tt.auth {
+			t.Errorf("ca %q required %t: client auth %s want %s", tt.caFile, tt.require, auth, tt.auth)
+		}
+	}
+
+	if _, err := NewReloader(certFile, "", "", false); !errors.Is(err, ErrKeyPair) {
+		t.Fatalf("expected %s, got %v", ErrKeyPair, err)
+	}
+	if _, err := ClientConfig("", certFile, "", ""); !errors.Is(err, ErrKeyPair) {
+		t.Fatalf("expected %s, got %v", ErrKeyPair, err)
+	}
+}
@@ -0,0 +1,15 @@
+package certs
+
+import (
+	"github.com/rs/zerolog"
+)
+
+// Option configures a Reloader.
+type Option func(*Reloader)
+
+// WithLogger sets the logger reloads are reported to, stderr by default.
+func WithLogger(l zerolog.Logger) Option {
+	return func(r *Reloader) {
+		r.logger = l
+	}
+}
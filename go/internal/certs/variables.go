@@ -0,0 +1,21 @@
+package certs
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ReloadInterval is the minimum period between checks for changes to certificate files on disk.
+const ReloadInterval = time.Duration(5) * time.Second
+
+var (
+	ErrKeyPair       = errors.New("both a certificate and key must be provided")
+	ErrCARequired    = errors.New("a CA certificate is required to verify client certificates")
+	ErrNoCertificate = errors.New("no certificates found")
+)
+
+// logger represents a configured instance of zerolog.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Logger().Output(zerolog.ConsoleWriter{Out: os.Stderr})
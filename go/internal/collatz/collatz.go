@@ -0,0 +1,69 @@
+package collatz
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+)
+
+// Name the collatz generator is registered under.
+const Name = "collatz"
+
+// Generator produces the Collatz trajectory of the seed.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "the collatz trajectory of the seed, halving even values and tripling plus one odd values, cycling 4, 2, 1 once reached"
+}
+
+// Params lists the parameters accepted, the collatz generator accepts none.
+func (Generator) Params() []generator.Param {
+	return nil
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the trajectory of the seed.
+func (Generator) Iterator(seed *big.Int, _ map[string]string) (generator.Iterator, error) {
+	return NewIterator(seed), nil
+}
+
+// Iterator produces the Collatz trajectory.
+type Iterator struct {
+	seed  *big.Int
+	value *big.Int
+}
+
+// Next returns the next value of the trajectory.
+func (it *Iterator) Next() *big.Int {
+	value := new(big.Int).Set(it.value)
+	if it.value.Bit(0) == 0 {
+		it.value.Rsh(it.value, 1)
+	} else {
+		it.value.Mul(it.value, big.NewInt(3))
+		it.value.Add(it.value, big.NewInt(1))
+	}
+
+	return value
+}
+
+// Reset returns the iterator to the seed.
+func (it *Iterator) Reset() {
+	it.value.Set(it.seed)
+}
+
+// NewIterator creates an iterator producing the trajectory of the seed.
+func NewIterator(seed *big.Int) *Iterator {
+	it := &Iterator{seed: new(big.Int).Abs(seed), value: new(big.Int)}
+	it.Reset()
+
+	return it
+}
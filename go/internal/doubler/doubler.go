@@ -1,37 +1,75 @@
 package doubler
 
 import (
-	"exercise/internal/state"
-	v1 "exercise/pkg/ably/v1"
+	"exercise/internal/generator"
 	"math/big"
 )
 
 const (
+	// Name the doubler generator is registered under.
+	Name = "doubler"
+
 	// Multiplier to be used for incrementing the value
 	Multiplier = 2
 )
 
-// GetSequence generates a sequence of values and pushes them to the supplied channel.
-func GetSequence(qty int64, seed *big.Int) ([]*big.Int, error) {
-	seq := make([]*big.Int, qty)
-	initVal := new(big.Int)
-	initVal.Set(seed)
-	seq[0] = seed
-
-	for cntr := int64(1); cntr < qty; cntr++ {
-		initVal.Mul(initVal, big.NewInt(Multiplier))
-		value := new(big.Int)
-		value.Set(initVal)
-		seq[cntr] = value
-	}
+// Generator produces the sequence of values doubling from the seed.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "a sequence of values starting at the seed where each value is double the last"
+}
+
+// Params lists the parameters accepted, the doubler accepts none.
+func (Generator) Params() []generator.Param {
+	return nil
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the values doubling from the seed.
+func (Generator) Iterator(seed *big.Int, _ map[string]string) (generator.Iterator, error) {
+	return NewIterator(seed), nil
+}
+
+// Iterator produces the sequence of values doubling from the seed.
+type Iterator struct {
+	seed  *big.Int
+	value *big.Int
+}
+
+// Next returns the next value of the sequence.
+func (it *Iterator) Next() *big.Int {
+	value := new(big.Int).Set(it.value)
+	it.value.Mul(it.value, big.NewInt(Multiplier))
+
+	return value
+}
+
+// Reset returns the iterator to the seed.
+func (it *Iterator) Reset() {
+	it.value.Set(it.seed)
+}
 
-	return seq, nil
+// SeekTo moves the iterator to position, the value at a position is the seed multiplied by Multiplier^position.
+func (it *Iterator) SeekTo(position int64) {
+	it.value.Exp(big.NewInt(Multiplier), big.NewInt(position), nil)
+	it.value.Mul(it.value, it.seed)
 }
 
-// GetRequest build a suitable v1.DoublerRequest from the provided state.Stateful
-func GetRequest(s state.Stateful) *v1.Request {
-	return &v1.Request{
-		Qty:  s.Require() + 1, // +1 to include seed
-		Seed: s.Last().Int64(),
+// NewIterator creates an iterator doubling from the seed.
+func NewIterator(seed *big.Int) *Iterator {
+	return &Iterator{
+		seed:  new(big.Int).Set(seed),
+		value: new(big.Int).Set(seed),
 	}
 }
@@ -0,0 +1,53 @@
+package doubler
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+	"testing"
+)
+
+func TestIterator(t *testing.T) {
+	t.Parallel()
+	seed := big.NewInt(3)
+	it := NewIterator(seed)
+
+	// the seed and values returned belong to the caller, so changing them leaves the sequence intact
+	seed.SetInt64(100)
+	first := it.Next()
+	first.SetInt64(100)
+	for i, want := range []int64{6, 12, 24} {
+		if value := it.Next(); value.Int64() != want {
+			t.Fatalf("value %d is %s want %d", i+1, value, want)
+		}
+	}
+
+	it.Reset()
+	if value := it.Next(); value.Int64() != 3 {
+		t.Fatalf("reset to %s", value)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	t.Parallel()
+	g := Generator{}
+	it, err := g.Iterator(big.NewInt(5), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := it.(generator.RandomAccess); !ok {
+		t.Fatal("iterator cannot seek")
+	}
+
+	// seeking to any position, forwards or back, continues the sequence generated from the seed
+	sequential := NewIterator(big.NewInt(5))
+	var values []*big.Int
+	for i := 0; i < 200; i++ {
+		values = append(values, sequential.Next())
+	}
+	for _, position := range []int64{0, 150, 1, 199, 64} {
+		generator.Skip(it, position)
+		if value := it.Next(); value.Cmp(values[position]) != 0 {
+			t.Fatalf("value at %d is %s want %s", position, value, values[position])
+		}
+	}
+}
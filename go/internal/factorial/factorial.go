@@ -0,0 +1,77 @@
+package factorial
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+)
+
+// Name the factorial generator is registered under.
+const Name = "factorial"
+
+// Generator produces the factorials starting at the factorial of the seed.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "the factorials n!, (n+1)!, (n+2)!... starting at the factorial of the seed"
+}
+
+// Params lists the parameters accepted, the factorial generator accepts none.
+func (Generator) Params() []generator.Param {
+	return nil
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the factorials from the seed.
+func (Generator) Iterator(seed *big.Int, _ map[string]string) (generator.Iterator, error) {
+	if seed.Sign() < 0 || !seed.IsInt64() {
+		return nil, generator.Invalid(generator.Seed, "must be non-negative")
+	}
+
+	return NewIterator(seed.Int64()), nil
+}
+
+// Iterator produces the factorials, each the last multiplied by the next integer.
+type Iterator struct {
+	start int64
+	// n is the integer whose factorial is the next value.
+	n     int64
+	value *big.Int
+}
+
+// Next returns the next value of the sequence.
+func (it *Iterator) Next() *big.Int {
+	value := new(big.Int).Set(it.value)
+	it.n++
+	it.value.Mul(it.value, big.NewInt(it.n))
+
+	return value
+}
+
+// Reset returns the iterator to the factorial of the seed.
+func (it *Iterator) Reset() {
+	it.SeekTo(0)
+}
+
+// SeekTo moves the iterator to position, the value at a position is the factorial of the seed plus position.
+func (it *Iterator) SeekTo(position int64) {
+	it.n = it.start + position
+	it.value = new(big.Int).MulRange(1, it.n)
+}
+
+// NewIterator creates an iterator producing the factorials from start.
+func NewIterator(start int64) *Iterator {
+	it := &Iterator{start: start}
+	it.Reset()
+
+	return it
+}
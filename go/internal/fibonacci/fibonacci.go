@@ -0,0 +1,154 @@
+package fibonacci
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+)
+
+const (
+	// Name the fibonacci generator is registered under.
+	Name = "fibonacci"
+
+	// LucasName the lucas generator is registered under.
+	LucasName = "lucas"
+)
+
+// Generator produces the Fibonacci numbers starting at the index given by the seed.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "the fibonacci numbers 0, 1, 1, 2, 3, 5... starting at the index given by the seed"
+}
+
+// Params lists the parameters accepted, the fibonacci generator accepts none.
+func (Generator) Params() []generator.Param {
+	return nil
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the fibonacci numbers from the index given by the seed.
+func (Generator) Iterator(seed *big.Int, _ map[string]string) (generator.Iterator, error) {
+	if err := index(seed); err != nil {
+		return nil, err
+	}
+
+	return NewIterator(seed.Int64(), false), nil
+}
+
+// Lucas produces the Lucas numbers starting at the index given by the seed.
+type Lucas struct{}
+
+// Name uniquely identifies the generator.
+func (Lucas) Name() string {
+	return LucasName
+}
+
+// Description summarises the sequence generated.
+func (Lucas) Description() string {
+	return "the lucas numbers 2, 1, 3, 4, 7, 11... starting at the index given by the seed"
+}
+
+// Params lists the parameters accepted, the lucas generator accepts none.
+func (Lucas) Params() []generator.Param {
+	return nil
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Lucas) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the lucas numbers from the index given by the seed.
+func (Lucas) Iterator(seed *big.Int, _ map[string]string) (generator.Iterator, error) {
+	if err := index(seed); err != nil {
+		return nil, err
+	}
+
+	return NewIterator(seed.Int64(), true), nil
+}
+
+// index checks the seed is usable as the index the sequence starts at.
+func index(seed *big.Int) error {
+	if seed.Sign() < 0 || !seed.IsInt64() {
+		return generator.Invalid(generator.Seed, "must be a non-negative index")
+	}
+
+	return nil
+}
+
+// Iterator produces the Fibonacci or Lucas numbers, each the sum of the two before it.
+type Iterator struct {
+	start int64
+	lucas bool
+	// a and b are the values at the next and following positions.
+	a, b *big.Int
+}
+
+// Next returns the next value of the sequence.
+func (it *Iterator) Next() *big.Int {
+	value := new(big.Int).Set(it.a)
+	it.a.Add(it.a, it.b)
+	it.a, it.b = it.b, it.a
+
+	return value
+}
+
+// Reset returns the iterator to the index given by the seed.
+func (it *Iterator) Reset() {
+	it.SeekTo(0)
+}
+
+// SeekTo moves the iterator to position, calculating the values directly by fast doubling.
+func (it *Iterator) SeekTo(position int64) {
+	f, g := fib(it.start + position)
+	if it.lucas {
+		// L(n) = 2F(n+1) - F(n) and L(n+1) = 2F(n+2) - F(n+1)
+		h := new(big.Int).Add(f, g)
+		l := new(big.Int).Lsh(g, 1)
+		l.Sub(l, f)
+		m := new(big.Int).Lsh(h, 1)
+		m.Sub(m, g)
+		f, g = l, m
+	}
+
+	it.a, it.b = f, g
+}
+
+// fib returns F(n) and F(n+1) using the fast doubling identities
+// F(2k) = F(k)(2F(k+1) - F(k)) and F(2k+1) = F(k)^2 + F(k+1)^2.
+func fib(n int64) (*big.Int, *big.Int) {
+	a, b := big.NewInt(0), big.NewInt(1)
+	for bit := 62; bit >= 0; bit-- {
+		c := new(big.Int).Lsh(b, 1)
+		c.Sub(c, a)
+		c.Mul(c, a)
+		d := new(big.Int).Mul(a, a)
+		d.Add(d, new(big.Int).Mul(b, b))
+		a, b = c, d
+
+		if n>>uint(bit)&1 == 1 {
+			a, b = b, new(big.Int).Add(c, d)
+		}
+	}
+
+	return a, b
+}
+
+// NewIterator creates an iterator producing the Fibonacci numbers, or the Lucas numbers if lucas,
+// from index start.
+func NewIterator(start int64, lucas bool) *Iterator {
+	it := &Iterator{start: start, lucas: lucas}
+	it.Reset()
+
+	return it
+}
@@ -0,0 +1,161 @@
+// Package framing encodes the messages of a sequence stream carried over a WebSocket.
+//
+// The client opens the stream with a GenerateRequest, its frame type choosing the framing of the stream.
+// Text frames carry JSON, the request as its protobuf JSON mapping and each following frame an object holding
+// either a "response" or the "status" the stream finished with. Binary frames carry protobuf, the request as is
+// and each following frame a Kind byte followed by a Response or google.rpc.Status.
+package framing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// Kind identifies the message carried by a binary frame.
+type Kind byte
+
+const (
+	// Response frames carry a value, the checksum or a going away notice.
+	Response Kind = iota + 1
+	// Status frames carry the status the stream finished with, the last frame before the connection closes.
+	Status
+)
+
+var ErrFrame = errors.New("malformed frame")
+
+// envelope is the JSON form of the frames sent by the server.
+type envelope struct {
+	Response json.RawMessage `json:"response,omitempty"`
+	Status   json.RawMessage `json:"status,omitempty"`
+}
+
+// EncodeRequest encodes the request opening a stream as a frame of the given type.
+func EncodeRequest(messageType int, req *v1.GenerateRequest) ([]byte, error) {
+	if messageType == websocket.BinaryMessage {
+		return proto.Marshal(req)
+	}
+
+	return protojson.Marshal(req)
+}
+
+// DecodeRequest decodes the request opening a stream.
+func DecodeRequest(messageType int, data []byte) (*v1.GenerateRequest, error) {
+	req := &v1.GenerateRequest{}
+
+	var err error
+	switch messageType {
+	case websocket.BinaryMessage:
+		err = proto.Unmarshal(data, req)
+	case websocket.TextMessage:
+		err = protojson.Unmarshal(data, req)
+	default:
+		err = fmt.Errorf("unexpected frame type %d", messageType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFrame, err)
+	}
+
+	return req, nil
+}
+
+// encode encodes a message of the kind as a frame of the given type.
+func encode(messageType int, kind Kind, m proto.Message) ([]byte, error) {
+	if messageType == websocket.BinaryMessage {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]byte{byte(kind)}, b...), nil
+	}
+
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	if kind == Status {
+		return json.Marshal(envelope{Status: b})
+	}
+
+	return json.Marshal(envelope{Response: b})
+}
+
+// EncodeResponse encodes a response as a frame of the given type.
+func EncodeResponse(messageType int, res *v1.Response) ([]byte, error) {
+	return encode(messageType, Response, res)
+}
+
+// EncodeStatus encodes the status a stream finished with as a frame of the given type, nil is encoded as OK.
+func EncodeStatus(messageType int, err error) ([]byte, error) {
+	st := status.Convert(err)
+	if err == nil {
+		st = status.New(codes.OK, "")
+	}
+
+	return encode(messageType, Status, st.Proto())
+}
+
+// DecodeResponse decodes a frame sent by the server, the status of a stream which failed is returned as its error
+// and a stream which finished successfully returns a nil response.
+func DecodeResponse(messageType int, data []byte) (*v1.Response, error) {
+	res := &v1.Response{}
+	st := &spb.Status{}
+	var kind Kind
+
+	switch messageType {
+	case websocket.BinaryMessage:
+		if len(data) == 0 {
+			return nil, ErrFrame
+		}
+		kind = Kind(data[0])
+		switch kind {
+		case Response:
+			if err := proto.Unmarshal(data[1:], res); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrFrame, err)
+			}
+		case Status:
+			if err := proto.Unmarshal(data[1:], st); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrFrame, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown kind %d", ErrFrame, kind)
+		}
+	case websocket.TextMessage:
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFrame, err)
+		}
+		switch {
+		case env.Response != nil:
+			kind = Response
+			if err := protojson.Unmarshal(env.Response, res); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrFrame, err)
+			}
+		case env.Status != nil:
+			kind = Status
+			if err := protojson.Unmarshal(env.Status, st); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrFrame, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: neither a response nor status", ErrFrame)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unexpected frame type %d", ErrFrame, messageType)
+	}
+
+	if kind == Status {
+		return nil, status.FromProto(st).Err()
+	}
+
+	return res, nil
+}
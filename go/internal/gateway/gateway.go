@@ -0,0 +1,508 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"exercise/internal/auth"
+	"exercise/internal/doubler"
+	"exercise/internal/mux"
+	"exercise/internal/random"
+	"exercise/internal/service"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rs/cors"
+	"github.com/rs/zerolog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// reserved query parameters describe the request, any other is passed to the generator as a parameter.
+var reserved = map[string]bool{
+	"qty": true, "seed": true, "last": true, "interval_ms": true, "integrity": true, "token": true, "format": true,
+	"client_id": true, "access_token": true,
+}
+
+// event is the JSON form of a response, numbers are rendered as decimal strings as they may exceed any JSON number.
+type event struct {
+	Sequence  *int64 `json:"sequence,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Total     string `json:"total,omitempty"`
+	Checksum  string `json:"checksum,omitempty"`
+	Chain     string `json:"chain,omitempty"`
+	Token     string `json:"token,omitempty"`
+	GoingAway bool   `json:"going_away,omitempty"`
+}
+
+// failure is the JSON form of the status a stream failed with.
+type failure struct {
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Violations []string `json:"violations,omitempty"`
+	RetryAfter float64  `json:"retry_after,omitempty"`
+}
+
+// decimal renders the big-endian bytes of a number as a decimal string, empty when there are none.
+func decimal(b []byte) string {
+	if b == nil {
+		return ""
+	}
+
+	return new(big.Int).SetBytes(b).String()
+}
+
+// stream adapts an HTTP response to the server side of the sequence rpcs, writing each response as an event.
+type stream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	format  string
+	started bool
+	logger  zerolog.Logger
+}
+
+func (s *stream) Context() context.Context     { return s.ctx }
+func (s *stream) SetHeader(metadata.MD) error  { return nil }
+func (s *stream) SendHeader(metadata.MD) error { return nil }
+func (s *stream) SetTrailer(metadata.MD)       {}
+func (s *stream) RecvMsg(interface{}) error    { return io.EOF }
+func (s *stream) SendMsg(m interface{}) error {
+	res, ok := m.(*v1.Response)
+	if !ok {
+		return status.Errorf(codes.Internal, "unable to send %T", m)
+	}
+
+	return s.Send(res)
+}
+
+// start writes the response headers for the format, the status can no longer be changed once started.
+func (s *stream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+
+	if s.format == NDJSON {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("X-Accel-Buffering", "no")
+	}
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// write delivers data as an event of the named type, the id being the token to resume from.
+func (s *stream) write(name, id string, data interface{}) error {
+	s.start()
+
+	line, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if s.format == NDJSON {
+		_, err = fmt.Fprintf(s.w, "%s\n", line)
+	} else {
+		if id != "" {
+			_, err = fmt.Fprintf(s.w, "id: %s\n", id)
+		}
+		if err == nil {
+			_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, line)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
+
+// Send writes the response as a value, checksum or going-away event.
+func (s *stream) Send(res *v1.Response) error {
+	token := ""
+	if res.Token != nil {
+		token = base64.RawURLEncoding.EncodeToString(res.Token)
+	}
+	ev := event{Token: token, GoingAway: res.GoingAway}
+	if len(res.Chain) > 0 {
+		ev.Chain = hex.EncodeToString(res.Chain)
+	}
+
+	switch {
+	case res.GoingAway:
+		return s.write("going-away", token, ev)
+	case res.Token == nil:
+		ev.Checksum = decimal(res.Checksum)
+		if ev.Checksum == "" {
+			ev.Checksum = "0"
+		}
+
+		return s.write("checksum", "", ev)
+	default:
+		sequence := res.Sequence
+		ev.Sequence = &sequence
+		ev.Value = decimal(res.Value)
+		if ev.Value == "" {
+			ev.Value = "0"
+		}
+		ev.Total = decimal(res.Total)
+
+		return s.write("value", token, ev)
+	}
+}
+
+// fail reports the status the stream failed with, as the response status and body if nothing has been
+// written yet otherwise as a final error event. Quotas exceeded carry how long to wait in the Retry-After header.
+func (s *stream) fail(err error) {
+	st := status.Convert(err)
+	f := failure{Code: st.Code().String(), Message: st.Message()}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				f.Violations = append(f.Violations, v.GetField()+" "+v.GetDescription())
+			}
+		case *errdetails.RetryInfo:
+			f.RetryAfter = d.GetRetryDelay().AsDuration().Seconds()
+		}
+	}
+
+	if s.started {
+		if err := s.write("error", "", f); err != nil {
+			s.logger.Debug().Err(err).Msg("Unable to report stream failure")
+		}
+
+		return
+	}
+
+	s.started = true
+	s.w.Header().Set("Content-Type", "application/json")
+	if f.RetryAfter > 0 {
+		s.w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter+0.999)))
+	}
+	s.w.WriteHeader(httpStatus(st.Code()))
+	_ = json.NewEncoder(s.w).Encode(f)
+}
+
+// httpStatus maps the code a stream failed with to the closest HTTP status.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// responder sends responses through the stream as the generated handlers do, so interceptors may wrap the stream.
+type responder struct {
+	grpc.ServerStream
+}
+
+func (r responder) Send(res *v1.Response) error {
+	return r.ServerStream.SendMsg(res)
+}
+
+// Gateway serves the sequences of the service over HTTP, GET /v1/{generator} streams the generator's values
+// as Server-Sent Events, or newline delimited JSON, resuming from the token in the Last-Event-ID header.
+// The rpcs' messages may also be exchanged over a WebSocket at /ws, and the rpcs called with gRPC-Web or Connect.
+// Streams pass through the same interceptors as the rpcs so are authenticated, limited and measured alike.
+type Gateway struct {
+	service     *service.Service
+	interceptor grpc.StreamServerInterceptor
+	unary       grpc.UnaryServerInterceptor
+	cors        *cors.Cors
+	logger      zerolog.Logger
+	// active tracks the WebSocket streams, which the server no longer tracks once upgraded
+	active sync.WaitGroup
+}
+
+// format negotiates the format events are delivered in, NDJSON when asked for by query or Accept header.
+func format(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	if strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		return NDJSON
+	}
+
+	return SSE
+}
+
+// request builds the request from the query parameters, the token to resume from is taken from the
+// Last-Event-ID header in preference to the token parameter.
+func request(r *http.Request) (*v1.Request, error) {
+	query := r.URL.Query()
+	req := &v1.Request{Params: map[string]string{}}
+
+	ints := map[string]*int64{"qty": &req.Qty, "seed": &req.Seed, "last": &req.Last}
+	for name, field := range ints {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s must be an integer", name)
+			}
+			*field = n
+		}
+	}
+
+	if value := query.Get("interval_ms"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "interval_ms must be a non-negative integer")
+		}
+		req.IntervalMs = uint32(n)
+	}
+
+	if value := query.Get("integrity"); value != "" {
+		integrity, ok := v1.Integrity_value["INTEGRITY_"+strings.ToUpper(value)]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "integrity %q is not supported", value)
+		}
+		req.Integrity = v1.Integrity(integrity)
+	}
+
+	token := r.Header.Get(LastEventIDHeader)
+	if token == "" {
+		token = query.Get("token")
+	}
+	if token != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "token is malformed")
+		}
+		req.Token = decoded
+	}
+
+	for name, values := range query {
+		if !reserved[name] && len(values) > 0 {
+			req.Params[name] = values[0]
+		}
+	}
+
+	return req, nil
+}
+
+// tlsStateKey carries the state of a connection whose TLS was terminated by a mux.
+type tlsStateKey struct{}
+
+// incoming builds the context of a stream as the rpcs see it, carrying the client-id and credentials as metadata
+// and the client's address, and certificate when verified, as its peer. Browsers unable to set headers, as an
+// EventSource is, may supply the client-id and bearer token by the client_id and access_token query parameters.
+func incoming(r *http.Request) context.Context {
+	query := r.URL.Query()
+	md := metadata.MD{}
+	id := r.Header.Get(ClientIDHeader)
+	if id == "" {
+		id = query.Get("client_id")
+	}
+	if id != "" {
+		md.Set("client-id", id)
+	}
+	authorization := r.Header.Get("Authorization")
+	if token := query.Get("access_token"); authorization == "" && token != "" {
+		authorization = auth.Scheme + " " + token
+	}
+	if authorization != "" {
+		md.Set(auth.MetadataKey, authorization)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	p := &peer.Peer{Addr: &net.TCPAddr{}}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	} else if state, ok := r.Context().Value(tlsStateKey{}).(tls.ConnectionState); ok {
+		p.AuthInfo = credentials.TLSInfo{State: state}
+	}
+
+	return peer.NewContext(ctx, p)
+}
+
+// ServeHTTP streams the generator named by the path.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, Prefix)
+	if !strings.HasPrefix(r.URL.Path, Prefix) || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	s := &stream{ctx: incoming(r), w: w, format: format(r), logger: g.logger}
+	if s.format != SSE && s.format != NDJSON {
+		s.fail(status.Errorf(codes.InvalidArgument, "format must be %s or %s", SSE, NDJSON))
+
+		return
+	}
+
+	req, err := request(r)
+	if err != nil {
+		s.fail(err)
+
+		return
+	}
+
+	method, handler := g.handler(name, req)
+	info := &grpc.StreamServerInfo{FullMethod: "/" + v1.Service_ServiceDesc.ServiceName + "/" + method, IsServerStream: true}
+	if err := g.interceptor(g.service, s, info, handler); err != nil {
+		s.fail(err)
+	}
+}
+
+// handler returns the rpc serving the named generator and the handler invoking it with the request.
+func (g *Gateway) handler(name string, req *v1.Request) (string, grpc.StreamHandler) {
+	switch name {
+	case doubler.Name:
+		return "Doubler", func(_ interface{}, ss grpc.ServerStream) error {
+			return g.service.Doubler(req, responder{ss})
+		}
+	case random.Name:
+		return "Random", func(_ interface{}, ss grpc.ServerStream) error {
+			return g.service.Random(req, responder{ss})
+		}
+	default:
+		return "Generate", func(_ interface{}, ss grpc.ServerStream) error {
+			return g.service.Generate(&v1.GenerateRequest{Generator: name, Request: req}, responder{ss})
+		}
+	}
+}
+
+// Handler returns the handler serving every endpoint of the gateway, permitting cross-origin calls when configured.
+func (g *Gateway) Handler() http.Handler {
+	routes := http.NewServeMux()
+	routes.Handle(Prefix, g)
+	routes.HandleFunc(WebSocketPath, g.ServeWebSocket)
+	routes.HandleFunc("/"+v1.Service_ServiceDesc.ServiceName+"/", g.ServeRPC)
+
+	if g.cors == nil {
+		return routes
+	}
+
+	return g.cors.Handler(routes)
+}
+
+// Serve listens on addr until the context is done, over TLS when a config is supplied.
+func (g *Gateway) Serve(ctx context.Context, addr string, config *tls.Config) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		config = config.Clone()
+		config.NextProtos = []string{"h2", "http/1.1"}
+		listener = tls.NewListener(listener, config)
+	}
+
+	return g.ServeListener(ctx, listener)
+}
+
+// ServeListener serves connections accepted by the listener until the context is done.
+// Active streams are given ShutdownTimeout to finish, they should first be drained by the service.
+func (g *Gateway) ServeListener(ctx context.Context, listener net.Listener) error {
+	srv := &http.Server{
+		// HTTP/2 connections handed over by a mux which terminated TLS, or without TLS, begin with the preface
+		Handler:           h2c.NewHandler(g.Handler(), &http2.Server{}),
+		ReadHeaderTimeout: ReadHeaderTimeout,
+		// connections accepted by a mux which terminated TLS carry its state for the handlers
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			if state, ok := mux.ConnectionState(conn); ok {
+				return context.WithValue(ctx, tlsStateKey{}, state)
+			}
+
+			return ctx
+		},
+	}
+
+	// serving stops as soon as shutdown begins, which then waits for the active streams to finish
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			_ = srv.Close()
+		}
+
+		sockets := make(chan struct{})
+		go func() {
+			g.active.Wait()
+			close(sockets)
+		}()
+		select {
+		case <-sockets:
+		case <-shutdownCtx.Done():
+		}
+	}()
+
+	// the listener may be closed before the context is done by a mux it is shared with
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	<-stopped
+
+	return nil
+}
+
+// NewGateway creates a gateway to the service.
+func NewGateway(svc *service.Service, opts ...Option) *Gateway {
+	g := &Gateway{
+		service:     svc,
+		interceptor: grpcMiddleware.ChainStreamServer(),
+		unary:       grpcMiddleware.ChainUnaryServer(),
+		logger:      logger,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"exercise/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// sse is an event received as a Server-Sent Event or line of NDJSON, which carries neither an id nor type.
+type sse struct {
+	id    string
+	name  string
+	event event
+}
+
+// get requests the stream at path with the headers, returning its events once it ends.
+func get(t *testing.T, srv *httptest.Server, path string, query url.Values, header http.Header) []sse {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("%s responded %d", path, res.StatusCode)
+	}
+
+	var events []sse
+	scanner := bufio.NewScanner(res.Body)
+	if res.Header.Get("Content-Type") == "application/x-ndjson" {
+		for scanner.Scan() {
+			var e sse
+			if err := json.Unmarshal(scanner.Bytes(), &e.event); err != nil {
+				t.Fatalf("line %q: %s", scanner.Text(), err)
+			}
+			events = append(events, e)
+		}
+
+		return events
+	}
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("%s responded with %s", path, res.Header.Get("Content-Type"))
+	}
+	var e sse
+	for scanner.Scan() {
+		field, value, _ := cut(scanner.Text(), ": ")
+		switch field {
+		case "id":
+			e.id = value
+		case "event":
+			e.name = value
+		case "data":
+			if err := json.Unmarshal([]byte(value), &e.event); err != nil {
+				t.Fatalf("data %q: %s", value, err)
+			}
+		case "":
+			events = append(events, e)
+			e = sse{}
+		default:
+			t.Fatalf("unexpected field %q", field)
+		}
+	}
+
+	return events
+}
+
+// cut slices s around the first separator.
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
+
+// received returns the decimal values of the events and the checksum ending them.
+func received(t *testing.T, events []sse) ([]string, string) {
+	t.Helper()
+
+	if len(events) == 0 {
+		t.Fatal("no events received")
+	}
+	var values []string
+	for _, e := range events[:len(events)-1] {
+		if e.event.Sequence == nil || e.event.Token == "" {
+			t.Fatalf("expected a value, got %+v", e.event)
+		}
+		values = append(values, e.event.Value)
+	}
+	last := events[len(events)-1].event
+	if last.Checksum == "" || last.Sequence != nil {
+		t.Fatalf("expected the checksum, got %+v", last)
+	}
+
+	return values, last.Checksum
+}
+
+func TestSSE(t *testing.T) {
+	t.Parallel()
+	srv := start(t)
+
+	events := get(t, srv, Prefix+"doubler", url.Values{"qty": {"4"}, "seed": {"5"}}, nil)
+	values, checksum := received(t, events)
+	if strings.Join(values, ",") != "5,10,20,40" || checksum != "75" {
+		t.Fatalf("received %v checksum %s", values, checksum)
+	}
+
+	// each value is identified by the token to resume after it, the checksum by nothing
+	for i, e := range events {
+		switch {
+		case i < len(events)-1 && (e.name != "value" || e.id != e.event.Token || *e.event.Sequence != int64(i)):
+			t.Fatalf("event %d is %s with id %q", i, e.name, e.id)
+		case i == len(events)-1 && (e.name != "checksum" || e.id != ""):
+			t.Fatalf("final event is %s with id %q", e.name, e.id)
+		}
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	t.Parallel()
+	srv := start(t)
+
+	// the format is chosen by query parameter or Accept header
+	for _, header := range []http.Header{nil, {"Accept": {"application/x-ndjson"}}} {
+		query := url.Values{"qty": {"3"}, "seed": {"1"}}
+		if header == nil {
+			query.Set("format", NDJSON)
+		}
+		values, checksum := received(t, get(t, srv, Prefix+"doubler", query, header))
+		if strings.Join(values, ",") != "1,2,4" || checksum != "7" {
+			t.Fatalf("received %v checksum %s", values, checksum)
+		}
+	}
+}
+
+func TestSSEResume(t *testing.T) {
+	t.Parallel()
+	srv := start(t)
+
+	// an EventSource can set no headers, so identifies itself by query parameter
+	query := url.Values{"qty": {"5"}, "seed": {"1"}, "client_id": {"browser"}}
+	first := get(t, srv, Prefix+"doubler", query, nil)
+	_, checksum := received(t, first)
+
+	// reconnecting, it resumes after the id of the last event received
+	resumed := get(t, srv, Prefix+"doubler", query, http.Header{LastEventIDHeader: {first[1].id}})
+	values, resumedChecksum := received(t, resumed)
+	if strings.Join(values, ",") != "4,8,16" || resumedChecksum != checksum || *resumed[0].event.Sequence != 2 {
+		t.Fatalf("resumed with %v checksum %s want checksum %s", values, resumedChecksum, checksum)
+	}
+
+	// the token parameter resumes a stream opened afresh, the Last-Event-ID header taking precedence over it
+	query.Set("token", first[3].id)
+	values, _ = received(t, get(t, srv, Prefix+"doubler", query, nil))
+	if strings.Join(values, ",") != "16" {
+		t.Fatalf("resumed from the token parameter with %v", values)
+	}
+	values, _ = received(t, get(t, srv, Prefix+"doubler", query, http.Header{LastEventIDHeader: {first[2].id}}))
+	if strings.Join(values, ",") != "8,16" {
+		t.Fatalf("resumed from the header with %v", values)
+	}
+}
+
+func TestIncomingFromQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		target        string
+		header        http.Header
+		clientID      string
+		authorization string
+	}{
+		{target: "/v1/doubler?client_id=query&access_token=secret", clientID: "query", authorization: auth.Scheme + " secret"},
+		{
+			target:        "/v1/doubler?client_id=query&access_token=secret",
+			header:        http.Header{ClientIDHeader: {"header"}, "Authorization": {auth.Scheme + " other"}},
+			clientID:      "header",
+			authorization: auth.Scheme + " other",
+		},
+		{target: "/v1/doubler"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		for name, values := range tt.header {
+			r.Header[name] = values
+		}
+		md, _ := metadata.FromIncomingContext(incoming(r))
+		if id := strings.Join(md.Get("client-id"), ","); id != tt.clientID {
+			t.Errorf("%s: client-id %q want %q", tt.target, id, tt.clientID)
+		}
+		if authorization := strings.Join(md.Get(auth.MetadataKey), ","); authorization != tt.authorization {
+			t.Errorf("%s: authorization %q want %q", tt.target, authorization, tt.authorization)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package gateway
+
+import (
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/rs/cors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// Option configures a Gateway.
+type Option func(*Gateway)
+
+// WithStreamInterceptors sets the interceptors streams pass through in order, as they do the streaming rpcs.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.interceptor = grpcMiddleware.ChainStreamServer(interceptors...)
+	}
+}
+
+// WithUnaryInterceptors sets the interceptors unary calls pass through in order, as they do the unary rpcs.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.unary = grpcMiddleware.ChainUnaryServer(interceptors...)
+	}
+}
+
+// WithLogger sets the logger the gateway reports to, stderr by default.
+func WithLogger(l zerolog.Logger) Option {
+	return func(g *Gateway) {
+		g.logger = l
+	}
+}
+
+// WithCORS allows the origins permitted by the policy to call the gateway from a browser.
+func WithCORS(policy *cors.Cors) Option {
+	return func(g *Gateway) {
+		g.cors = policy
+	}
+}
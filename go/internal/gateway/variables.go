@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Prefix of the path each generator is served under, e.g. /v1/doubler.
+	Prefix = "/v1/"
+
+	// WebSocketPath is where a stream is served over a WebSocket, the generator being named by the request.
+	WebSocketPath = "/ws"
+
+	// ClientIDHeader carries the client-id the state of a stream is retained against.
+	ClientIDHeader = "Client-Id"
+
+	// LastEventIDHeader carries the token of the last event received, the stream resumes at the next value.
+	LastEventIDHeader = "Last-Event-ID"
+
+	// ReadHeaderTimeout bounds how long the gateway waits for request headers.
+	ReadHeaderTimeout = time.Duration(5) * time.Second
+
+	// WriteTimeout bounds how long the gateway waits to write a frame to a WebSocket.
+	WriteTimeout = time.Duration(10) * time.Second
+
+	// MaxMessageSize bounds the size of a request message called with gRPC-Web or Connect, or sent over a WebSocket.
+	MaxMessageSize = 4 << 20
+
+	// ShutdownTimeout is how long active streams are given to finish once the gateway is stopped.
+	ShutdownTimeout = time.Duration(5) * time.Second
+)
+
+// Formats the events of a stream may be delivered in.
+const (
+	// SSE delivers each response as a Server-Sent Event whose id is the token to resume from.
+	SSE = "sse"
+	// NDJSON delivers each response as a line of JSON.
+	NDJSON = "ndjson"
+)
+
+// Content types identifying the protocol an rpc is called with from a browser, suffixed by +proto or +json.
+const (
+	// GRPCWeb frames each message as gRPC does, the status following as a trailers frame.
+	GRPCWeb = "application/grpc-web"
+	// GRPCWebText is GRPCWeb base64 encoded for clients unable to handle binary responses.
+	GRPCWebText = "application/grpc-web-text"
+	// ConnectStreaming frames each message of a Connect stream, unary Connect calls use application/proto or application/json.
+	ConnectStreaming = "application/connect"
+)
+
+// CORSHeaders are the request headers browsers must be allowed to send to call the gateway.
+var CORSHeaders = []string{
+	"Content-Type", "Authorization", ClientIDHeader, LastEventIDHeader, "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout",
+	"Connect-Protocol-Version", "Connect-Timeout-Ms", "Connect-Accept-Encoding", "Connect-Content-Encoding",
+}
+
+// CORSExposedHeaders are the response headers browsers are allowed to read.
+var CORSExposedHeaders = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin", "Retry-After"}
+
+// logger represents a configured instance of zerolog.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Logger().Output(zerolog.ConsoleWriter{Out: os.Stderr})
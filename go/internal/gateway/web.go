@@ -0,0 +1,465 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"exercise/internal/doubler"
+	"exercise/internal/random"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// Flags of the envelope framing each message of gRPC-Web and Connect.
+const (
+	flagCompressed byte = 0x01
+	flagEndStream  byte = 0x02
+	flagTrailers   byte = 0x80
+)
+
+// streaming lists the rpcs served to browsers, whether each streams its responses.
+var streaming = map[string]bool{"Doubler": true, "Random": true, "Generate": true, "ListGenerators": false}
+
+// codec marshals messages as protobuf, or the protobuf JSON mapping when true.
+type codec bool
+
+func (c codec) marshal(m proto.Message) ([]byte, error) {
+	if c {
+		return protojson.Marshal(m)
+	}
+
+	return proto.Marshal(m)
+}
+
+func (c codec) unmarshal(data []byte, m proto.Message) error {
+	if c {
+		return protojson.Unmarshal(data, m)
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+// protocol frames the messages of an rpc called from a browser.
+type protocol interface {
+	// read decodes the request message from the body.
+	read(body io.Reader, m proto.Message) error
+	// send writes a response message.
+	send(m proto.Message) error
+	// finish ends the response with the status the rpc finished with.
+	finish(err error)
+}
+
+// readEnvelope decodes the single enveloped message of a request.
+func readEnvelope(body io.Reader, c codec, m proto.Message) error {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return status.Error(codes.InvalidArgument, "request message missing")
+	}
+	if prefix[0]&flagCompressed != 0 {
+		return status.Error(codes.Unimplemented, "compressed messages are not supported")
+	}
+
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > MaxMessageSize {
+		return status.Errorf(codes.ResourceExhausted, "request message exceeds %d bytes", MaxMessageSize)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return status.Error(codes.InvalidArgument, "request message truncated")
+	}
+
+	if err := c.unmarshal(data, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "request message malformed: %s", err)
+	}
+
+	return nil
+}
+
+// envelope prefixes data with its flags and length.
+func envelope(flags byte, data []byte) []byte {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = flags
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+
+	return append(frame, data...)
+}
+
+// flush delivers what has been written to the client immediately.
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// grpcWeb frames an rpc as gRPC-Web, the status is sent as a trailers frame once the rpc finishes.
+// The text variant base64 encodes every frame.
+type grpcWeb struct {
+	w           http.ResponseWriter
+	codec       codec
+	contentType string
+	text        bool
+	started     bool
+	logger      zerolog.Logger
+}
+
+func (p *grpcWeb) read(body io.Reader, m proto.Message) error {
+	if p.text {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	return readEnvelope(body, p.codec, m)
+}
+
+// write writes a frame, starting the response with its headers should it not have started.
+func (p *grpcWeb) write(flags byte, data []byte) error {
+	if !p.started {
+		p.started = true
+		p.w.Header().Set("Content-Type", p.contentType)
+		p.w.WriteHeader(http.StatusOK)
+	}
+
+	frame := envelope(flags, data)
+	if p.text {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	if _, err := p.w.Write(frame); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	flush(p.w)
+
+	return nil
+}
+
+func (p *grpcWeb) send(m proto.Message) error {
+	data, err := p.codec.marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return p.write(0, data)
+}
+
+func (p *grpcWeb) finish(err error) {
+	st := status.Convert(err)
+
+	var trailers strings.Builder
+	fmt.Fprintf(&trailers, "grpc-status: %d\r\ngrpc-message: %s\r\n", st.Code(), percentEncode(st.Message()))
+	if len(st.Proto().GetDetails()) > 0 {
+		if details, err := proto.Marshal(st.Proto()); err == nil {
+			fmt.Fprintf(&trailers, "grpc-status-details-bin: %s\r\n", base64.RawStdEncoding.EncodeToString(details))
+		}
+	}
+
+	if err := p.write(flagTrailers, []byte(trailers.String())); err != nil {
+		p.logger.Debug().Err(err).Msg("Unable to report rpc status")
+	}
+}
+
+// percentEncode encodes a status message for a grpc-message trailer.
+func percentEncode(message string) string {
+	var b strings.Builder
+	for _, c := range []byte(message) {
+		if c >= ' ' && c <= '~' && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// connectError is the JSON form of the status a Connect rpc failed with.
+type connectError struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message,omitempty"`
+	Details []connectDetail `json:"details,omitempty"`
+}
+
+// connectDetail is a detail of a connectError, the base64 encoded protobuf of the named message.
+type connectDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// endStream is the JSON of the message ending a Connect stream, without an error if the stream succeeded.
+type endStream struct {
+	Error *connectError `json:"error,omitempty"`
+}
+
+// newConnectError converts the status an rpc failed with to its Connect form, whose codes are in snake case.
+func newConnectError(st *status.Status) *connectError {
+	var code strings.Builder
+	for i, c := range st.Code().String() {
+		if i > 0 && unicode.IsUpper(c) {
+			code.WriteByte('_')
+		}
+		code.WriteRune(unicode.ToLower(c))
+	}
+
+	e := &connectError{Code: code.String(), Message: st.Message()}
+	for _, detail := range st.Proto().GetDetails() {
+		e.Details = append(e.Details, connectDetail{
+			Type:  detail.GetTypeUrl()[strings.LastIndex(detail.GetTypeUrl(), "/")+1:],
+			Value: base64.RawStdEncoding.EncodeToString(detail.GetValue()),
+		})
+	}
+
+	return e
+}
+
+// connect frames an rpc with the Connect protocol, a unary rpc exchanges bare messages and reports
+// failure with an HTTP status whereas a stream is enveloped and ended by a message carrying the status.
+type connect struct {
+	w           http.ResponseWriter
+	codec       codec
+	contentType string
+	streaming   bool
+	started     bool
+	logger      zerolog.Logger
+}
+
+func (p *connect) read(body io.Reader, m proto.Message) error {
+	if p.streaming {
+		return readEnvelope(body, p.codec, m)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, MaxMessageSize+1))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "request message truncated")
+	}
+	if len(data) > MaxMessageSize {
+		return status.Errorf(codes.ResourceExhausted, "request message exceeds %d bytes", MaxMessageSize)
+	}
+	if err := p.codec.unmarshal(data, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "request message malformed: %s", err)
+	}
+
+	return nil
+}
+
+// start writes the response headers, the status can no longer be changed once started.
+func (p *connect) start() {
+	if !p.started {
+		p.started = true
+		p.w.Header().Set("Content-Type", p.contentType)
+		p.w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (p *connect) send(m proto.Message) error {
+	data, err := p.codec.marshal(m)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	p.start()
+	if p.streaming {
+		data = envelope(0, data)
+	}
+	if _, err := p.w.Write(data); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	flush(p.w)
+
+	return nil
+}
+
+func (p *connect) finish(err error) {
+	var end endStream
+	if err != nil {
+		end.Error = newConnectError(status.Convert(err))
+	}
+
+	if !p.streaming {
+		if end.Error == nil {
+			return
+		}
+		p.w.Header().Set("Content-Type", "application/json")
+		p.w.WriteHeader(httpStatus(status.Code(err)))
+		_ = json.NewEncoder(p.w).Encode(end.Error)
+
+		return
+	}
+
+	data, marshalErr := json.Marshal(end)
+	if marshalErr == nil {
+		p.start()
+		_, marshalErr = p.w.Write(envelope(flagEndStream, data))
+		flush(p.w)
+	}
+	if marshalErr != nil {
+		p.logger.Debug().Err(marshalErr).Msg("Unable to report rpc status")
+	}
+}
+
+// negotiate chooses the protocol of an rpc from the content type of its request, nil when none applies.
+func (g *Gateway) negotiate(w http.ResponseWriter, contentType string) protocol {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.SplitN(mediaType, "+", 2)
+	subtype := "proto"
+	if len(parts) == 2 {
+		subtype = parts[1]
+	}
+	if subtype != "proto" && subtype != "json" {
+		return nil
+	}
+	c := codec(subtype == "json")
+
+	switch parts[0] {
+	case GRPCWeb:
+		return &grpcWeb{w: w, codec: c, contentType: GRPCWeb + "+" + subtype, logger: g.logger}
+	case GRPCWebText:
+		return &grpcWeb{w: w, codec: c, contentType: GRPCWebText + "+" + subtype, text: true, logger: g.logger}
+	case ConnectStreaming:
+		return &connect{w: w, codec: c, contentType: mediaType, streaming: true, logger: g.logger}
+	case "application/proto", "application/json":
+		if len(parts) == 2 {
+			return nil
+		}
+
+		return &connect{w: w, codec: codec(mediaType == "application/json"), contentType: mediaType, logger: g.logger}
+	default:
+		return nil
+	}
+}
+
+// deadline applies the timeout requested by a Connect-Timeout-Ms or grpc-timeout header to the context.
+func deadline(ctx context.Context, header http.Header) (context.Context, context.CancelFunc) {
+	if ms, err := strconv.ParseInt(header.Get("Connect-Timeout-Ms"), 10, 64); err == nil && ms > 0 {
+		return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+	}
+
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	if timeout := header.Get("Grpc-Timeout"); len(timeout) > 1 {
+		unit, ok := units[timeout[len(timeout)-1]]
+		if n, err := strconv.ParseInt(timeout[:len(timeout)-1], 10, 64); ok && err == nil && n > 0 {
+			return context.WithTimeout(ctx, time.Duration(n)*unit)
+		}
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// call adapts an rpc called from a browser to the server side of the streaming rpcs.
+type call struct {
+	ctx      context.Context
+	protocol protocol
+}
+
+func (c *call) Context() context.Context     { return c.ctx }
+func (c *call) SetHeader(metadata.MD) error  { return nil }
+func (c *call) SendHeader(metadata.MD) error { return nil }
+func (c *call) SetTrailer(metadata.MD)       {}
+func (c *call) RecvMsg(interface{}) error    { return io.EOF }
+func (c *call) SendMsg(m interface{}) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "unable to send %T", m)
+	}
+
+	return c.protocol.send(msg)
+}
+
+// ServeRPC serves the rpcs of the service to browsers with gRPC-Web or Connect, chosen by the request's content type.
+// Calls pass through the same interceptors as the rpcs, only the bidirectional Subscribe cannot be called.
+func (g *Gateway) ServeRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/"+v1.Service_ServiceDesc.ServiceName+"/")
+	p := g.negotiate(w, r.Header.Get("Content-Type"))
+	if c, ok := p.(*connect); ok {
+		if stream, known := streaming[method]; known && stream != c.streaming {
+			p = nil
+		}
+	}
+	if p == nil {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+
+		return
+	}
+
+	ctx, cancel := deadline(incoming(r), r.Header)
+	defer cancel()
+
+	fullMethod := "/" + v1.Service_ServiceDesc.ServiceName + "/" + method
+	var name string
+	var req *v1.Request
+	switch method {
+	case "ListGenerators":
+		p.finish(g.listGenerators(ctx, p, r.Body, fullMethod))
+
+		return
+	case "Doubler", "Random":
+		name = doubler.Name
+		if method == "Random" {
+			name = random.Name
+		}
+		req = &v1.Request{}
+		if err := p.read(r.Body, req); err != nil {
+			p.finish(err)
+
+			return
+		}
+	case "Generate":
+		generate := &v1.GenerateRequest{}
+		if err := p.read(r.Body, generate); err != nil {
+			p.finish(err)
+
+			return
+		}
+		name, req = generate.GetGenerator(), generate.GetRequest()
+	default:
+		p.finish(status.Errorf(codes.Unimplemented, "method %s cannot be called from a browser", method))
+
+		return
+	}
+
+	method, handler := g.handler(name, req)
+	info := &grpc.StreamServerInfo{FullMethod: "/" + v1.Service_ServiceDesc.ServiceName + "/" + method, IsServerStream: true}
+	p.finish(g.interceptor(g.service, &call{ctx: ctx, protocol: p}, info, handler))
+}
+
+// listGenerators calls the unary ListGenerators rpc through the unary interceptors, sending the response.
+func (g *Gateway) listGenerators(ctx context.Context, p protocol, body io.Reader, fullMethod string) error {
+	req := &v1.ListGeneratorsRequest{}
+	if err := p.read(body, req); err != nil {
+		return err
+	}
+
+	info := &grpc.UnaryServerInfo{Server: g.service, FullMethod: fullMethod}
+	res, err := g.unary(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return g.service.ListGenerators(ctx, req.(*v1.ListGeneratorsRequest))
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.send(res.(proto.Message))
+}
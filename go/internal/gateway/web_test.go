@@ -0,0 +1,273 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"exercise/internal/pacing"
+	"exercise/internal/service"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/cors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// Origin is the origin permitted to call the gateway from a browser.
+const Origin = "https://app.example.com"
+
+// start serves the gateway to a service sending values without delay, everything is stopped once the test ends.
+func start(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+
+	svc := service.NewService(service.WithPacing(pacing.Policy{Mode: pacing.Burst}))
+	srv := httptest.NewServer(NewGateway(svc, opts...).Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		_ = svc.Close()
+	})
+
+	return srv
+}
+
+// post calls the rpc with the body, failing unless the response has the status.
+func post(t *testing.T, srv *httptest.Server, method, contentType string, body []byte, code int) []byte {
+	t.Helper()
+
+	res, err := http.Post(srv.URL+"/"+v1.Service_ServiceDesc.ServiceName+"/"+method, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != code {
+		t.Fatalf("%s responded %d want %d: %s", method, res.StatusCode, code, data)
+	}
+
+	return data
+}
+
+// frame is a message, trailers or end of stream enveloped in a response.
+type frame struct {
+	flags byte
+	data  []byte
+}
+
+// frames splits the body into the frames it envelopes.
+func frames(t *testing.T, body []byte) []frame {
+	t.Helper()
+
+	var framed []frame
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("truncated frame prefix %x", body)
+		}
+		length := int(binary.BigEndian.Uint32(body[1:5]))
+		if len(body) < 5+length {
+			t.Fatalf("frame of %d bytes truncated to %d", length, len(body)-5)
+		}
+		framed = append(framed, frame{flags: body[0], data: body[5 : 5+length]})
+		body = body[5+length:]
+	}
+
+	return framed
+}
+
+// decodeText decodes a gRPC-Web text body, whose frames are each base64 encoded with their own padding.
+func decodeText(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	var decoded []byte
+	for len(body) > 0 {
+		prefix, err := base64.StdEncoding.DecodeString(string(body[:8]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := base64.StdEncoding.EncodedLen(5 + int(binary.BigEndian.Uint32(prefix[1:5])))
+		frame, err := base64.StdEncoding.DecodeString(string(body[:encoded]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, frame...)
+		body = body[encoded:]
+	}
+
+	return decoded
+}
+
+// values unmarshals the responses of the frames, returning the values sent and whether the checksum followed them.
+func values(t *testing.T, framed []frame, unmarshal func([]byte, proto.Message) error) ([]int64, bool) {
+	t.Helper()
+
+	var sent []int64
+	checksum := false
+	for _, f := range framed {
+		res := &v1.Response{}
+		if err := unmarshal(f.data, res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Token == nil {
+			checksum = true
+
+			continue
+		}
+		sent = append(sent, new(big.Int).SetBytes(res.Value).Int64())
+	}
+
+	return sent, checksum
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestGRPCWeb(t *testing.T) {
+	t.Parallel()
+	srv := start(t)
+
+	req, err := proto.Marshal(&v1.Request{Qty: 4, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	framed := frames(t, post(t, srv, "Doubler", GRPCWeb+"+proto", envelope(0, req), http.StatusOK))
+	trailers := framed[len(framed)-1]
+	if trailers.flags != flagTrailers || !strings.Contains(string(trailers.data), "grpc-status: 0\r\n") {
+		t.Fatalf("stream ended with %x: %q", trailers.flags, trailers.data)
+	}
+	sent, checksum := values(t, framed[:len(framed)-1], proto.Unmarshal)
+	if !equal(sent, []int64{3, 6, 12, 24}) || !checksum {
+		t.Fatalf("sent %v with checksum %t", sent, checksum)
+	}
+
+	// the text variant frames the same messages base64 encoded
+	body := post(t, srv, "ListGenerators", GRPCWebText+"+proto", []byte(base64.StdEncoding.EncodeToString(envelope(0, nil))), http.StatusOK)
+	framed = frames(t, decodeText(t, body))
+	list := &v1.ListGeneratorsResponse{}
+	if len(framed) != 2 || proto.Unmarshal(framed[0].data, list) != nil || len(list.Generators) == 0 {
+		t.Fatalf("listed generators as %d frames", len(framed))
+	}
+
+	// a failure is reported by the trailers alone
+	req, _ = proto.Marshal(&v1.GenerateRequest{Generator: "unknown", Request: &v1.Request{Qty: 1}})
+	framed = frames(t, post(t, srv, "Generate", GRPCWeb+"+proto", envelope(0, req), http.StatusOK))
+	if len(framed) != 1 || framed[0].flags != flagTrailers || strings.Contains(string(framed[0].data), "grpc-status: 0\r\n") {
+		t.Fatalf("unknown generator answered with %d frames", len(framed))
+	}
+}
+
+func TestConnect(t *testing.T) {
+	t.Parallel()
+	srv := start(t)
+
+	// a stream is enveloped and ended by a message carrying no error
+	req, err := protojson.Marshal(&v1.GenerateRequest{Generator: "doubler", Request: &v1.Request{Qty: 3, Seed: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	framed := frames(t, post(t, srv, "Generate", ConnectStreaming+"+json", envelope(0, req), http.StatusOK))
+	end := framed[len(framed)-1]
+	if end.flags != flagEndStream || strings.TrimSpace(string(end.data)) != "{}" {
+		t.Fatalf("stream ended with %x: %s", end.flags, end.data)
+	}
+	sent, checksum := values(t, framed[:len(framed)-1], protojson.Unmarshal)
+	if !equal(sent, []int64{1, 2, 4}) || !checksum {
+		t.Fatalf("sent %v with checksum %t", sent, checksum)
+	}
+
+	req, _ = protojson.Marshal(&v1.GenerateRequest{Generator: "unknown", Request: &v1.Request{Qty: 1}})
+	framed = frames(t, post(t, srv, "Generate", ConnectStreaming+"+json", envelope(0, req), http.StatusOK))
+	var failed endStream
+	if len(framed) != 1 || json.Unmarshal(framed[0].data, &failed) != nil || failed.Error == nil {
+		t.Fatalf("unknown generator answered with %d frames", len(framed))
+	}
+
+	// a unary call exchanges bare messages, reporting failure by the HTTP status
+	list := &v1.ListGeneratorsResponse{}
+	if err := protojson.Unmarshal(post(t, srv, "ListGenerators", "application/json", []byte("{}"), http.StatusOK), list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Generators) == 0 {
+		t.Fatal("no generators listed")
+	}
+	var unary connectError
+	if err := json.Unmarshal(post(t, srv, "ListGenerators", "application/json", []byte("{"), http.StatusBadRequest), &unary); err != nil {
+		t.Fatal(err)
+	}
+	if unary.Code != "invalid_argument" {
+		t.Fatalf("malformed request failed with %s", unary.Code)
+	}
+
+	// streams must be called with the streaming content type and unary calls without
+	post(t, srv, "Doubler", "application/proto", nil, http.StatusUnsupportedMediaType)
+	post(t, srv, "ListGenerators", ConnectStreaming+"+proto", envelope(0, nil), http.StatusUnsupportedMediaType)
+}
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+	srv := start(t, WithCORS(cors.New(cors.Options{
+		AllowedOrigins: []string{Origin},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: CORSHeaders,
+		ExposedHeaders: CORSExposedHeaders,
+	})))
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, srv.URL+"/"+v1.Service_ServiceDesc.ServiceName+"/Doubler", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,client-id,grpc-timeout")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		return res
+	}
+
+	if res := preflight(Origin); res.Header.Get("Access-Control-Allow-Origin") != Origin {
+		t.Fatalf("preflight from %s allowed %q", Origin, res.Header.Get("Access-Control-Allow-Origin"))
+	}
+	if res := preflight("https://elsewhere.example.com"); res.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("preflight from another origin allowed %q", res.Header.Get("Access-Control-Allow-Origin"))
+	}
+
+	// the status of a gRPC-Web call is readable by the page
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/"+v1.Service_ServiceDesc.ServiceName+"/ListGenerators", bytes.NewReader(envelope(0, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", Origin)
+	req.Header.Set("Content-Type", GRPCWeb+"+proto")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if exposed := res.Header.Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "Grpc-Status") {
+		t.Fatalf("exposed headers %q", exposed)
+	}
+}
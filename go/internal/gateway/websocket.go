@@ -0,0 +1,150 @@
+package gateway
+
+import (
+	"context"
+	"exercise/internal/framing"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// socket adapts a WebSocket connection to the server side of the sequence rpcs, responses are framed
+// as the request was, in JSON for a text frame and protobuf for a binary frame.
+type socket struct {
+	ctx         context.Context
+	conn        *websocket.Conn
+	messageType int
+	mu          sync.Mutex
+	logger      zerolog.Logger
+}
+
+func (s *socket) Context() context.Context     { return s.ctx }
+func (s *socket) SetHeader(metadata.MD) error  { return nil }
+func (s *socket) SendHeader(metadata.MD) error { return nil }
+func (s *socket) SetTrailer(metadata.MD)       {}
+func (s *socket) RecvMsg(interface{}) error    { return io.EOF }
+func (s *socket) SendMsg(m interface{}) error {
+	res, ok := m.(*v1.Response)
+	if !ok {
+		return status.Errorf(codes.Internal, "unable to send %T", m)
+	}
+
+	return s.Send(res)
+}
+
+// write sends a frame, bounded by WriteTimeout so a stalled client cannot hold the stream open.
+func (s *socket) write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+
+	return s.conn.WriteMessage(s.messageType, data)
+}
+
+// Send writes the response as a frame.
+func (s *socket) Send(res *v1.Response) error {
+	data, err := framing.EncodeResponse(s.messageType, res)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return s.write(data)
+}
+
+// finish sends the status the stream finished with and closes the connection normally.
+func (s *socket) finish(err error) {
+	data, encodeErr := framing.EncodeStatus(s.messageType, err)
+	if encodeErr == nil {
+		encodeErr = s.write(data)
+	}
+	if encodeErr != nil {
+		s.logger.Debug().Err(encodeErr).Msg("Unable to report stream status")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	closing := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = s.conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(WriteTimeout))
+}
+
+// watch cancels the stream once the client closes the connection, nothing more is expected of the client.
+func (s *socket) watch(cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		if _, _, err := s.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// checkOrigin accepts WebSockets opened by pages served by the same host, or an origin permitted to call the gateway.
+func (g *Gateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return g.cors != nil && g.cors.OriginAllowed(r)
+}
+
+// ServeWebSocket streams the generator named by the first frame, a GenerateRequest, over the connection.
+// The stream passes through the same interceptors as the rpcs, its status is sent as the last frame.
+func (g *Gateway) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: g.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		g.logger.Debug().Err(err).Msg("Unable to upgrade to a WebSocket")
+
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	g.active.Add(1)
+	defer g.active.Done()
+
+	ctx, cancel := context.WithCancel(incoming(r))
+	defer cancel()
+
+	conn.SetReadLimit(MaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(ReadHeaderTimeout))
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		g.logger.Debug().Err(err).Msg("No request received over the WebSocket")
+
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	s := &socket{ctx: ctx, conn: conn, messageType: messageType, logger: g.logger}
+	if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage {
+		s.messageType = websocket.TextMessage
+	}
+
+	req, err := framing.DecodeRequest(messageType, data)
+	if err != nil {
+		s.finish(status.Error(codes.InvalidArgument, err.Error()))
+
+		return
+	}
+	go s.watch(cancel)
+
+	method, handler := g.handler(req.GetGenerator(), req.GetRequest())
+	info := &grpc.StreamServerInfo{FullMethod: "/" + v1.Service_ServiceDesc.ServiceName + "/" + method, IsServerStream: true}
+	s.finish(g.interceptor(g.service, s, info, handler))
+}
@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"bytes"
+	"exercise/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocketReadLimit(t *testing.T) {
+	t.Parallel()
+
+	svc := service.NewService()
+	defer svc.Close()
+	srv := httptest.NewServer(http.HandlerFunc(NewGateway(svc).ServeWebSocket))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the server stops reading once the limit is exceeded, so the write may or may not complete
+	_ = conn.WriteMessage(websocket.BinaryMessage, bytes.Repeat([]byte{0}, MaxMessageSize+1))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Fatalf("expected the connection to be closed as the message is too big, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// Param describes a parameter accepted by a generator.
+type Param struct {
+	Name        string
+	Description string
+	Default     string
+}
+
+// Iterator produces the values of a sequence on demand, so a stream holds a single value at a time
+// however many values are requested.
+type Iterator interface {
+	// Next returns the next value of the sequence.
+	Next() *big.Int
+	// Reset returns the iterator to the start of the sequence.
+	Reset()
+}
+
+// RandomAccess is implemented by iterators able to move directly to a position in the sequence
+// without generating the values before it.
+type RandomAccess interface {
+	// SeekTo moves the iterator so the next value returned is at position.
+	SeekTo(position int64)
+}
+
+// Seeded is implemented by generators whose values do not reveal the seed, so a sequence can only be regenerated
+// by the client from a seed it chose itself rather than one chosen by the service.
+type Seeded interface {
+	// RequiresSeed reports whether the request must carry the seed given the parameters.
+	RequiresSeed(params map[string]string) bool
+}
+
+// Skip moves the iterator so the next value returned is at position, seeking directly when supported
+// otherwise generating and discarding the values before it.
+func Skip(it Iterator, position int64) {
+	if seeker, ok := it.(RandomAccess); ok {
+		seeker.SeekTo(position)
+
+		return
+	}
+
+	it.Reset()
+	for i := int64(0); i < position; i++ {
+		it.Next()
+	}
+}
+
+// Generator produces a sequence of values, new sequences are made available by registering a Generator.
+type Generator interface {
+	// Name uniquely identifies the generator.
+	Name() string
+	// Description summarises the sequence generated.
+	Description() string
+	// Params lists the parameters accepted in addition to the quantity and seed.
+	Params() []Param
+	// Deterministic reports whether the same request, with the parameters resolved, always generates
+	// the same sequence, allowing a stream to be resumed without state being retained.
+	Deterministic(params map[string]string) bool
+	// Iterator produces the sequence generated from the seed and parameters.
+	Iterator(seed *big.Int, params map[string]string) (Iterator, error)
+}
+
+// Registry holds the generators available to the service by name.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	generators map[string]Generator
+}
+
+// Register adds the generator to the registry, names must be unique.
+func (r *Registry) Register(g Generator) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.generators[g.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicate, g.Name())
+	}
+	r.generators[g.Name()] = g
+
+	return nil
+}
+
+// Get returns the generator registered under name.
+func (r *Registry) Get(name string) (Generator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, ok := r.generators[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+
+	return g, nil
+}
+
+// List returns every registered generator ordered by name.
+func (r *Registry) List() []Generator {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Generator, 0, len(r.generators))
+	for _, g := range r.generators {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+
+	return list
+}
+
+// Validate checks that only parameters declared by the generator have been supplied,
+// returning the parameters with defaults applied for any omitted.
+func Validate(g Generator, params map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(g.Params()))
+	declared := make(map[string]bool, len(g.Params()))
+	for _, p := range g.Params() {
+		declared[p.Name] = true
+		resolved[p.Name] = p.Default
+	}
+
+	for name, value := range params {
+		if !declared[name] {
+			return nil, Invalid(name, "is not accepted by %s", g.Name())
+		}
+		resolved[name] = value
+	}
+
+	return resolved, nil
+}
+
+// NewRegistry creates a registry holding the supplied generators.
+func NewRegistry(generators ...Generator) (*Registry, error) {
+	r := &Registry{generators: map[string]Generator{}}
+	for _, g := range generators {
+		if err := r.Register(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
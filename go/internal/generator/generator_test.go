@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// counter counts up from the seed by the step.
+type counter struct {
+	name string
+}
+
+func (c counter) Name() string                         { return c.name }
+func (c counter) Description() string                  { return "counts from the seed" }
+func (c counter) Deterministic(map[string]string) bool { return true }
+
+func (c counter) Params() []Param {
+	return []Param{{Name: "step", Description: "added to each value", Default: "1"}}
+}
+
+func (c counter) Iterator(seed *big.Int, params map[string]string) (Iterator, error) {
+	step, err := Int(params, "step")
+	if err != nil {
+		return nil, err
+	}
+	return &counting{seed: seed, step: step, next: new(big.Int).Set(seed)}, nil
+}
+
+type counting struct {
+	seed, step, next *big.Int
+	generated        int
+}
+
+func (it *counting) Next() *big.Int {
+	value := new(big.Int).Set(it.next)
+	it.next.Add(it.next, it.step)
+	it.generated++
+
+	return value
+}
+
+func (it *counting) Reset() { it.next.Set(it.seed) }
+
+// seeking counts able to seek directly to a position.
+type seeking struct {
+	*counting
+}
+
+func (it *seeking) SeekTo(position int64) {
+	it.next.Mul(it.step, big.NewInt(position)).Add(it.next, it.seed)
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewRegistry(counter{name: "b"}, counter{name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(counter{name: "a"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected %s, got %v", ErrDuplicate, err)
+	}
+	if _, err := NewRegistry(counter{name: "a"}, counter{name: "a"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expected %s, got %v", ErrDuplicate, err)
+	}
+	if _, err := r.Get("c"); !errors.Is(err, ErrUnknown) {
+		t.Fatalf("expected %s, got %v", ErrUnknown, err)
+	}
+
+	// generators are listed by name whatever the order registered
+	if err := r.Register(counter{name: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	var names string
+	for _, g := range r.List() {
+		names += g.Name()
+	}
+	if names != "abc" {
+		t.Fatalf("listed %s", names)
+	}
+	if g, err := r.Get("c"); err != nil || g.Name() != "c" {
+		t.Fatalf("got %v: %v", g, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	g := counter{name: "counter"}
+
+	resolved, err := Validate(g, nil)
+	if err != nil || len(resolved) != 1 || resolved["step"] != "1" {
+		t.Fatalf("resolved %v: %v", resolved, err)
+	}
+	if resolved, err = Validate(g, map[string]string{"step": "3"}); err != nil || resolved["step"] != "3" {
+		t.Fatalf("resolved %v: %v", resolved, err)
+	}
+
+	var paramErr *ParamError
+	if _, err := Validate(g, map[string]string{"stride": "3"}); !errors.As(err, &paramErr) || paramErr.Name != "stride" || !errors.Is(err, ErrParam) {
+		t.Fatalf("undeclared parameter accepted: %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	params := map[string]string{"int": "123456789012345678901234567890", "float": "1.5", "nan": "NaN", "inf": "+Inf", "word": "x"}
+
+	if n, err := Int(params, "int"); err != nil || n.String() != params["int"] {
+		t.Fatalf("parsed %s: %v", n, err)
+	}
+	if f, err := Float(params, "float"); err != nil || f != 1.5 {
+		t.Fatalf("parsed %v: %v", f, err)
+	}
+	for _, name := range []string{"word", "float"} {
+		if _, err := Int(params, name); !errors.Is(err, ErrParam) {
+			t.Errorf("%s parsed as an integer: %v", name, err)
+		}
+	}
+	for _, name := range []string{"word", "nan", "inf", "missing"} {
+		if _, err := Float(params, name); !errors.Is(err, ErrParam) {
+			t.Errorf("%s parsed as a number: %v", name, err)
+		}
+	}
+}
+
+func TestSkip(t *testing.T) {
+	t.Parallel()
+
+	for _, seekable := range []bool{false, true} {
+		counted := &counting{seed: big.NewInt(10), step: big.NewInt(3), next: big.NewInt(10)}
+		var it Iterator = counted
+		if seekable {
+			it = &seeking{counting: counted}
+		}
+
+		// skipping back as well as forward lands on the position, only generating the values before it when unable to seek
+		for _, position := range []int64{5, 2, 0, 7} {
+			counted.generated = 0
+			Skip(it, position)
+			skipped := counted.generated
+			if value := it.Next(); value.Int64() != 10+3*position {
+				t.Fatalf("seekable %t: skipped to %d returning %s", seekable, position, value)
+			}
+			want := int(position)
+			if seekable {
+				want = 0
+			}
+			if skipped != want {
+				t.Fatalf("seekable %t: generated %d values skipping to %d", seekable, skipped, position)
+			}
+		}
+	}
+}
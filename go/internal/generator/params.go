@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// ParamError describes a parameter the generator cannot accept, it wraps ErrParam.
+type ParamError struct {
+	// Name of the parameter, or Seed when the seed is unusable.
+	Name string
+	// Reason the value is not accepted.
+	Reason string
+}
+
+// Error describes the parameter and why it was not accepted.
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrParam, e.Name, e.Reason)
+}
+
+// Unwrap allows errors.Is to match ErrParam.
+func (e *ParamError) Unwrap() error {
+	return ErrParam
+}
+
+// Invalid builds the ParamError for the named parameter, the reason being formatted as per fmt.Sprintf.
+func Invalid(name, format string, args ...interface{}) error {
+	return &ParamError{Name: name, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Int parses the named parameter as an integer of any size.
+func Int(params map[string]string, name string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(params[name], 10)
+	if !ok {
+		return nil, Invalid(name, "must be an integer not %q", params[name])
+	}
+
+	return value, nil
+}
+
+// Float parses the named parameter as a finite floating point number.
+func Float(params map[string]string, name string) (float64, error) {
+	value, err := strconv.ParseFloat(params[name], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, Invalid(name, "must be a finite number not %q", params[name])
+	}
+
+	return value, nil
+}
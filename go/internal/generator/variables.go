@@ -0,0 +1,14 @@
+package generator
+
+import (
+	"errors"
+)
+
+// Seed names the seed in a ParamError, the seed being validated alongside the parameters.
+const Seed = "seed"
+
+var (
+	ErrDuplicate = errors.New("generator already registered")
+	ErrUnknown   = errors.New("unknown generator")
+	ErrParam     = errors.New("invalid parameter")
+)
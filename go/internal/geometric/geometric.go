@@ -0,0 +1,81 @@
+package geometric
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+)
+
+// Name the geometric generator is registered under.
+const Name = "geometric"
+
+// Generator produces the sequence of values starting at the seed where each is the last multiplied by a rational multiplier.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "a sequence of values starting at the seed where each value is the last multiplied by the multiplier, rounded down"
+}
+
+// Params lists the parameters accepted.
+func (Generator) Params() []generator.Param {
+	return []generator.Param{
+		{Name: "multiplier", Description: "the non-negative multiplier, an integer, decimal or fraction such as 3/2", Default: "3/2"},
+	}
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the values multiplying from the seed.
+func (Generator) Iterator(seed *big.Int, params map[string]string) (generator.Iterator, error) {
+	multiplier, ok := new(big.Rat).SetString(params["multiplier"])
+	if !ok || multiplier.Sign() < 0 {
+		return nil, generator.Invalid("multiplier", "must be a non-negative number not %q", params["multiplier"])
+	}
+
+	return NewIterator(seed, multiplier), nil
+}
+
+// Iterator produces the geometric sequence, the exact rational value is retained so rounding never accumulates.
+type Iterator struct {
+	seed       *big.Rat
+	multiplier *big.Rat
+	value      *big.Rat
+}
+
+// Next returns the next value of the sequence.
+func (it *Iterator) Next() *big.Int {
+	value := new(big.Int).Quo(it.value.Num(), it.value.Denom())
+	it.value.Mul(it.value, it.multiplier)
+
+	return value
+}
+
+// Reset returns the iterator to the seed.
+func (it *Iterator) Reset() {
+	it.value.Set(it.seed)
+}
+
+// SeekTo moves the iterator to position, the value at a position is the seed multiplied by multiplier^position.
+func (it *Iterator) SeekTo(position int64) {
+	num := new(big.Int).Exp(it.multiplier.Num(), big.NewInt(position), nil)
+	denom := new(big.Int).Exp(it.multiplier.Denom(), big.NewInt(position), nil)
+	it.value.SetFrac(num, denom)
+	it.value.Mul(it.value, it.seed)
+}
+
+// NewIterator creates an iterator multiplying from the seed.
+func NewIterator(seed *big.Int, multiplier *big.Rat) *Iterator {
+	return &Iterator{
+		seed:       new(big.Rat).SetInt(seed),
+		multiplier: new(big.Rat).Set(multiplier),
+		value:      new(big.Rat).SetInt(seed),
+	}
+}
@@ -0,0 +1,27 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+// Genesis returns the head of an empty chain.
+func Genesis() []byte {
+	return make([]byte, sha256.Size)
+}
+
+// Link returns the head of the chain once the value at position is appended to the chain ending in head,
+// each link hashes the previous head, the position and the value so any reordering or corruption
+// changes every head from that position onwards.
+func Link(head []byte, position int64, value *big.Int) []byte {
+	var index [8]byte
+	binary.BigEndian.PutUint64(index[:], uint64(position))
+
+	h := sha256.New()
+	h.Write(head)
+	h.Write(index[:])
+	h.Write(value.Bytes())
+
+	return h.Sum(nil)
+}
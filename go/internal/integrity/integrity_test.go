@@ -0,0 +1,59 @@
+package integrity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+// chain links the values from the genesis head, returning the head after each.
+func chain(values ...int64) [][]byte {
+	heads := make([][]byte, len(values))
+	head := Genesis()
+	for i, v := range values {
+		head = Link(head, int64(i), big.NewInt(v))
+		heads[i] = head
+	}
+
+	return heads
+}
+
+func TestGenesis(t *testing.T) {
+	t.Parallel()
+
+	if head := Genesis(); len(head) != sha256.Size || !bytes.Equal(head, make([]byte, sha256.Size)) {
+		t.Fatalf("genesis head %x", head)
+	}
+}
+
+func TestLink(t *testing.T) {
+	t.Parallel()
+	heads := chain(1, 2, 4, 8)
+
+	if !bytes.Equal(heads[3], chain(1, 2, 4, 8)[3]) {
+		t.Fatal("the same values linked to a different head")
+	}
+
+	// every head from the value corrupted or reordered onwards differs, those before it do not
+	for _, tt := range []struct {
+		name  string
+		heads [][]byte
+		from  int
+	}{
+		{name: "corrupted", heads: chain(1, 2, 5, 8), from: 2},
+		{name: "reordered", heads: chain(1, 4, 2, 8), from: 1},
+	} {
+		for i := range heads {
+			if changed := !bytes.Equal(tt.heads[i], heads[i]); changed != (i >= tt.from) {
+				t.Fatalf("%s: head at %d changed %t", tt.name, i, changed)
+			}
+		}
+	}
+	if bytes.Equal(Link(Genesis(), 1, big.NewInt(1)), heads[0]) {
+		t.Fatal("value linked at another position to the same head")
+	}
+	if bytes.Equal(Link(heads[0], 1, big.NewInt(2)), Link(heads[1], 1, big.NewInt(2))) {
+		t.Fatal("value linked to another head to the same head")
+	}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Metrics holds the collectors describing the behaviour of the server.
+// It records stream lifecycles via a grpc interceptor and the detail of each stream via the service.Observer hooks.
+type Metrics struct {
+	registry         *prometheus.Registry
+	streamsOpened    *prometheus.CounterVec
+	streamsCompleted *prometheus.CounterVec
+	streamsAborted   *prometheus.CounterVec
+	streamDuration   *prometheus.HistogramVec
+	valuesSent       *prometheus.CounterVec
+	sendLatency      *prometheus.HistogramVec
+	resumptions      *prometheus.CounterVec
+	statesEvicted    prometheus.Counter
+	states           prometheus.Gauge
+}
+
+// method returns the short name of the rpc from its full name.
+func method(fullMethod string) string {
+	return path.Base(fullMethod)
+}
+
+// StreamServerInterceptor records streams being opened and how they finished.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		name := method(info.FullMethod)
+		start := time.Now()
+		m.streamsOpened.WithLabelValues(name).Inc()
+
+		err := handler(srv, ss)
+
+		m.streamDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.streamsAborted.WithLabelValues(name, status.Code(err).String()).Inc()
+		} else {
+			m.streamsCompleted.WithLabelValues(name).Inc()
+		}
+
+		return err
+	}
+}
+
+// ValueSent records a value being sent on a stream and how long the send took.
+func (m *Metrics) ValueSent(method string, latency time.Duration) {
+	m.valuesSent.WithLabelValues(method).Inc()
+	m.sendLatency.WithLabelValues(method).Observe(latency.Seconds())
+}
+
+// Resumed records a stream being served from a stored state.
+func (m *Metrics) Resumed(method string) {
+	m.resumptions.WithLabelValues(method).Inc()
+}
+
+// Evicted records states being evicted from the store.
+func (m *Metrics) Evicted(count int) {
+	m.statesEvicted.Add(float64(count))
+}
+
+// StatesHeld records the number of states currently held in the store.
+func (m *Metrics) StatesHeld(count int) {
+	m.states.Set(float64(count))
+}
+
+// Handler returns the http.Handler exposing the metrics in the prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Serve exposes the metrics on /metrics at addr until the context is done.
+func (m *Metrics) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: ReadHeaderTimeout}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// NewMetrics creates and registers the server collectors alongside the standard go and process collectors.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		streamsOpened: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "streams_opened_total",
+			Help:      "Number of streams opened by rpc.",
+		}, []string{"method"}),
+		streamsCompleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "streams_completed_total",
+			Help:      "Number of streams which sent their full sequence by rpc.",
+		}, []string{"method"}),
+		streamsAborted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "streams_aborted_total",
+			Help:      "Number of streams which ended with an error by rpc and status code.",
+		}, []string{"method", "code"}),
+		streamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "stream_duration_seconds",
+			Help:      "Duration of streams by rpc.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 4, 10),
+		}, []string{"method"}),
+		valuesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "values_sent_total",
+			Help:      "Number of sequence values sent by rpc.",
+		}, []string{"method"}),
+		sendLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "send_latency_seconds",
+			Help:      "Time taken to send each value by rpc.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"method"}),
+		resumptions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "resumptions_total",
+			Help:      "Number of streams served from a stored state by rpc.",
+		}, []string{"method"}),
+		statesEvicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "states_evicted_total",
+			Help:      "Number of states evicted after exceeding their TTL.",
+		}),
+		states: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "states",
+			Help:      "Number of client states currently held.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.streamsOpened,
+		m.streamsCompleted,
+		m.streamsAborted,
+		m.streamDuration,
+		m.valuesSent,
+		m.sendLatency,
+		m.resumptions,
+		m.statesEvicted,
+		m.states,
+	)
+
+	return m
+}
@@ -0,0 +1,135 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/metrics"
+	"exercise/internal/pacing"
+	"exercise/internal/service"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// serve runs a service observed by the metrics, everything is stopped once the test ends.
+func serve(t *testing.T, m *metrics.Metrics) v1.ServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	svc := service.NewService(service.WithPacing(pacing.Policy{Mode: pacing.Burst}), service.WithObserver(m))
+	srv := grpc.NewServer(grpc.ChainStreamInterceptor(m.StreamServerInterceptor(), svc.StreamServerInterceptor()))
+	v1.RegisterServiceServer(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+
+	dialer := func(context.Context, string) (net.Conn, error) { return lis.Dial() }
+	conn, err := grpc.Dial("bufconn", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		srv.Stop()
+		_ = svc.Close()
+	})
+
+	return v1.NewServiceClient(conn)
+}
+
+// generate streams the sequence requested until it ends, returning the token of each value and how it ended.
+func generate(ctx context.Context, t *testing.T, client v1.ServiceClient, req *v1.GenerateRequest) ([][]byte, error) {
+	t.Helper()
+
+	stream, err := client.Generate(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens [][]byte
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		}
+		if err != nil {
+			return tokens, err
+		}
+		if res.Token != nil {
+			tokens = append(tokens, res.Token)
+		}
+	}
+}
+
+// exposition returns the metrics as scraped from the handler, a sample on each line.
+func exposition(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	return rec.Body.String()
+}
+
+func TestStreamCounters(t *testing.T) {
+	t.Parallel()
+	m := metrics.NewMetrics()
+	client := serve(t, m)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "client-id", "counted")
+
+	// the doubler sequence completes, then is resumed from its stored state before the last value
+	req := &v1.GenerateRequest{Generator: "doubler", Request: &v1.Request{Qty: 3, Seed: 1}}
+	tokens, err := generate(ctx, t, client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Request.Token = tokens[1]
+	if _, err := generate(ctx, t, client, req); err != nil {
+		t.Fatal(err)
+	}
+	_, err = generate(ctx, t, client, &v1.GenerateRequest{Generator: "unknown", Request: &v1.Request{Qty: 1}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("unknown generator failed with %v", err)
+	}
+
+	scraped := exposition(t, m)
+	for _, sample := range []string{
+		metrics.Namespace + `_streams_opened_total{method="Generate"} 3`,
+		metrics.Namespace + `_streams_completed_total{method="Generate"} 2`,
+		metrics.Namespace + `_streams_aborted_total{code="InvalidArgument",method="Generate"} 1`,
+		metrics.Namespace + `_values_sent_total{method="Generate"} 4`,
+		metrics.Namespace + `_send_latency_seconds_count{method="Generate"} 4`,
+		metrics.Namespace + `_stream_duration_seconds_count{method="Generate"} 3`,
+		metrics.Namespace + `_resumptions_total{method="Generate"} 1`,
+	} {
+		if !strings.Contains(scraped, sample+"\n") {
+			t.Errorf("expected %s in\n%s", sample, scraped)
+		}
+	}
+}
+
+func TestStateCounters(t *testing.T) {
+	t.Parallel()
+	m := metrics.NewMetrics()
+
+	m.Evicted(2)
+	m.Evicted(3)
+	m.StatesHeld(7)
+	m.StatesHeld(4)
+
+	// evictions accumulate while the states held are replaced by each measurement
+	scraped := exposition(t, m)
+	for _, sample := range []string{metrics.Namespace + "_states 4", metrics.Namespace + "_states_evicted_total 5"} {
+		if !strings.Contains(scraped, "\n"+sample+"\n") {
+			t.Errorf("expected %s in\n%s", sample, scraped)
+		}
+	}
+}
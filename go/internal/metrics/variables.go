@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"time"
+)
+
+const (
+	// Namespace prefixes the name of every metric exposed by the server.
+	Namespace = "ably_exercise"
+
+	// ReadHeaderTimeout bounds how long the metrics endpoint waits for request headers.
+	ReadHeaderTimeout = time.Duration(5) * time.Second
+)
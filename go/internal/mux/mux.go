@@ -0,0 +1,194 @@
+// Package mux serves native gRPC and HTTP on a single listener, sniffing the protocol each connection speaks.
+package mux
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"github.com/rs/zerolog"
+	"github.com/soheilhy/cmux"
+	"google.golang.org/grpc/credentials"
+)
+
+// ErrClientHandshake is returned should the credentials of the mux be used to dial, they only accept connections.
+var ErrClientHandshake = errors.New("mux credentials only accept connections")
+
+// Mux splits the connections accepted by a listener between the gRPC server and the HTTP server,
+// HTTP/2 connections whose first request carries the gRPC content-type are gRPC and any other HTTP, including
+// gRPC-Web and Connect over HTTP/2. TLS is terminated by the mux so that the protocol can be sniffed,
+// the gRPC server must then use its Credentials.
+type Mux struct {
+	mux    cmux.CMux
+	root   net.Listener
+	grpc   net.Listener
+	http   net.Listener
+	secure bool
+	logger zerolog.Logger
+}
+
+// alpn returns a copy of the config offering Protocols, including the configs resolved for each client.
+func alpn(config *tls.Config) *tls.Config {
+	config = config.Clone()
+	config.NextProtos = Protocols
+	if resolve := config.GetConfigForClient; resolve != nil {
+		config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			resolved, err := resolve(hello)
+			if err != nil || resolved == nil {
+				return resolved, err
+			}
+			resolved = resolved.Clone()
+			resolved.NextProtos = Protocols
+
+			return resolved, nil
+		}
+	}
+
+	return config
+}
+
+// listener translates the errors returned once the mux is closed into net.ErrClosed.
+type listener struct {
+	net.Listener
+	// settle is how many matchers sent SETTINGS whose acknowledgements are removed from the connections accepted.
+	settle int
+}
+
+func (l listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if errors.Is(err, cmux.ErrListenerClosed) || errors.Is(err, cmux.ErrServerClosed) {
+		return nil, net.ErrClosed
+	}
+	if err == nil && l.settle > 0 {
+		conn = &settled{Conn: conn, matchers: l.settle}
+	}
+
+	return conn, err
+}
+
+// GRPC returns the listener accepting gRPC connections, closing it closes the mux.
+func (m *Mux) GRPC() net.Listener {
+	return m.grpc
+}
+
+// HTTP returns the listener accepting HTTP connections.
+func (m *Mux) HTTP() net.Listener {
+	return m.http
+}
+
+// Credentials returns the transport credentials of the gRPC server, the connections accepted are already secured
+// by the mux when TLS is enabled.
+func (m *Mux) Credentials() credentials.TransportCredentials {
+	return terminated{secure: m.secure}
+}
+
+// Serve accepts connections until the mux is closed.
+func (m *Mux) Serve() error {
+	if err := m.mux.Serve(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Close stops accepting connections, connections already accepted are unaffected.
+func (m *Mux) Close() error {
+	m.mux.Close()
+
+	return m.root.Close()
+}
+
+// NewMux creates a mux accepting connections from the listener, over TLS when a config is supplied.
+func NewMux(l net.Listener, config *tls.Config, opts ...Option) *Mux {
+	if config != nil {
+		l = tls.NewListener(l, alpn(config))
+	}
+
+	// gRPC clients await the server's SETTINGS before making their first request, so the matchers send them
+	m := cmux.New(l)
+	matchers := []cmux.MatchWriter{
+		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", GRPCContentType),
+		cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", GRPCContentType+"+"),
+	}
+	grpc := m.MatchWithWriters(matchers...)
+	mx := &Mux{
+		mux:    m,
+		root:   l,
+		grpc:   listener{Listener: grpc},
+		http:   listener{Listener: m.Match(cmux.Any()), settle: len(matchers)},
+		secure: config != nil,
+		logger: logger,
+	}
+	for _, opt := range opts {
+		opt(mx)
+	}
+
+	// a failed TLS handshake or unrecognised protocol only loses the connection
+	m.HandleError(func(err error) bool {
+		mx.logger.Debug().Err(err).Msg("Unable to sniff connection")
+
+		return true
+	})
+
+	return mx
+}
+
+// ConnectionState returns the state of the TLS connection the mux accepted conn over.
+func ConnectionState(conn net.Conn) (tls.ConnectionState, bool) {
+	if c, ok := conn.(*settled); ok {
+		conn = c.Conn
+	}
+	if c, ok := conn.(*cmux.MuxConn); ok {
+		conn = c.Conn
+	}
+	if c, ok := conn.(*tls.Conn); ok {
+		return c.ConnectionState(), true
+	}
+
+	return tls.ConnectionState{}, false
+}
+
+// terminated are the credentials of connections whose TLS, if any, the mux terminated.
+type terminated struct {
+	secure bool
+}
+
+// ServerHandshake reports the TLS state of the connection as its auth info, nothing is exchanged.
+func (t terminated) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	state, ok := ConnectionState(conn)
+	if !ok {
+		return conn, insecureInfo{credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}, nil
+	}
+
+	return conn, credentials.TLSInfo{State: state, CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity}}, nil
+}
+
+func (t terminated) ClientHandshake(context.Context, string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, ErrClientHandshake
+}
+
+func (t terminated) Info() credentials.ProtocolInfo {
+	if t.secure {
+		return credentials.ProtocolInfo{SecurityProtocol: "tls", SecurityVersion: "1.2"}
+	}
+
+	return credentials.ProtocolInfo{SecurityProtocol: "insecure"}
+}
+
+func (t terminated) Clone() credentials.TransportCredentials {
+	return t
+}
+
+func (t terminated) OverrideServerName(string) error {
+	return nil
+}
+
+// insecureInfo is the auth info of a connection without TLS.
+type insecureInfo struct {
+	credentials.CommonAuthInfo
+}
+
+func (insecureInfo) AuthType() string {
+	return "insecure"
+}
@@ -0,0 +1,184 @@
+package mux_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"exercise/internal/gateway"
+	"exercise/internal/mux"
+	"exercise/internal/pacing"
+	"exercise/internal/service"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// certificate creates a self-signed certificate for 127.0.0.1, returning the server's config and a pool trusting it.
+func certificate(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mux"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+// serve splits a listener between the service's gRPC server and its gateway, over TLS when a config is supplied.
+// Everything is stopped once the test ends.
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mux.NewMux(lis, config)
+	svc := service.NewService(service.WithPacing(pacing.Policy{Mode: pacing.Burst}))
+	srv := grpc.NewServer(grpc.Creds(m.Credentials()))
+	v1.RegisterServiceServer(srv, svc)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() { _ = srv.Serve(m.GRPC()) }()
+	go func() { _ = gateway.NewGateway(svc).ServeListener(ctx, m.HTTP()) }()
+	go func() { _ = m.Serve() }()
+	t.Cleanup(func() {
+		cancel()
+		srv.Stop()
+		_ = m.Close()
+		_ = svc.Close()
+	})
+
+	return lis.Addr().String()
+}
+
+// h2 creates a client speaking HTTP/2 with prior knowledge when there is no pool to trust the server's TLS with.
+func h2(pool *x509.CertPool) *http.Client {
+	if pool != nil {
+		return &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+	}
+
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+}
+
+// call posts the body to ListGenerators with the content type, returning the response body.
+func call(t *testing.T, client *http.Client, url, contentType string, body []byte) []byte {
+	t.Helper()
+
+	url += "/" + v1.Service_ServiceDesc.ServiceName + "/ListGenerators"
+	res, err := client.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || res.ProtoMajor != 2 {
+		t.Fatalf("%s responded %d over HTTP/%d: %s", contentType, res.StatusCode, res.ProtoMajor, data)
+	}
+
+	return data
+}
+
+func TestMux(t *testing.T) {
+	t.Parallel()
+	config, pool := certificate(t)
+
+	tests := []struct {
+		name   string
+		config *tls.Config
+		pool   *x509.CertPool
+	}{
+		{name: "insecure"},
+		{name: "tls", config: config, pool: pool},
+	}
+	for _, tt := range tests {
+		addr := serve(t, tt.config)
+		scheme, creds := "http://", insecure.NewCredentials()
+		if tt.pool != nil {
+			scheme, creds = "https://", credentials.NewClientTLSFromCert(tt.pool, "")
+		}
+
+		// gRPC is served by the gRPC server
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		list, err := v1.NewServiceClient(conn).ListGenerators(context.Background(), &v1.ListGeneratorsRequest{})
+		if err != nil || len(list.Generators) == 0 {
+			t.Fatalf("%s: gRPC listed %d generators: %v", tt.name, len(list.GetGenerators()), err)
+		}
+
+		// gRPC-Web and Connect over HTTP/2 are served by the gateway, several calls sharing a connection
+		client := h2(tt.pool)
+		for i := 0; i < 3; i++ {
+			body := call(t, client, scheme+addr, gateway.GRPCWeb+"+proto", make([]byte, 5))
+			length := binary.BigEndian.Uint32(body[1:5])
+			if trailers := body[5+length:]; !strings.Contains(string(trailers), "grpc-status: 0") {
+				t.Fatalf("%s: gRPC-Web call %d ended with %q", tt.name, i, trailers)
+			}
+			list := &v1.ListGeneratorsResponse{}
+			if err := proto.Unmarshal(call(t, client, scheme+addr, "application/proto", nil), list); err != nil || len(list.Generators) == 0 {
+				t.Fatalf("%s: Connect call %d listed %d generators: %v", tt.name, i, len(list.Generators), err)
+			}
+		}
+
+		// as is HTTP/1
+		http1 := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: tt.pool, MinVersion: tls.VersionTLS12}}}
+		res, err := http1.Get(scheme + addr + gateway.Prefix + "doubler?qty=2&seed=1&format=ndjson")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || res.ProtoMajor != 1 || bytes.Count(body, []byte("\n")) != 3 {
+			t.Fatalf("%s: HTTP/%d responded %d: %s", tt.name, res.ProtoMajor, res.StatusCode, body)
+		}
+	}
+}
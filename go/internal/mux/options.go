@@ -0,0 +1,15 @@
+package mux
+
+import (
+	"github.com/rs/zerolog"
+)
+
+// Option configures a Mux.
+type Option func(*Mux)
+
+// WithLogger sets the logger the mux reports to, stderr by default.
+func WithLogger(l zerolog.Logger) Option {
+	return func(m *Mux) {
+		m.logger = l
+	}
+}
@@ -0,0 +1,110 @@
+package mux
+
+import (
+	"bytes"
+	"io"
+	"net"
+
+	"golang.org/x/net/http2"
+)
+
+// frameHeaderLen is the length of the header of every HTTP/2 frame.
+const frameHeaderLen = 9
+
+// settled is a connection accepted for the HTTP server from which the client's acknowledgements of the SETTINGS
+// sent by the gRPC matchers are removed. Those SETTINGS are sent so gRPC clients awaiting the server's before making
+// their first request can be sniffed, the HTTP server would otherwise take their acknowledgement as a protocol error
+// as it only expects acknowledgements of its own.
+type settled struct {
+	net.Conn
+	// matchers is how many matchers answered each SETTINGS the client sent before its first request with their own.
+	matchers int
+	// pending are the bytes read from the connection yet to be returned.
+	pending []byte
+	// sniffed reports the connection was found to open with the HTTP/2 preface or not.
+	sniffed bool
+	// unacked are the SETTINGS the matchers sent which the client has yet to acknowledge.
+	unacked int
+	// requested reports the headers of the first request have been read, the matchers send no SETTINGS after them.
+	requested bool
+	// passthrough reports nothing further is removed from the connection.
+	passthrough bool
+}
+
+func (c *settled) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.passthrough {
+			return c.Conn.Read(p)
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+
+	return n, nil
+}
+
+// next reads the preface, or the next frame should the connection be HTTP/2, into pending unless it acknowledges
+// the SETTINGS of a matcher in which case it is discarded.
+func (c *settled) next() error {
+	if !c.sniffed {
+		return c.sniff()
+	}
+
+	frame := make([]byte, frameHeaderLen)
+	if _, err := io.ReadFull(c.Conn, frame); err != nil {
+		return err
+	}
+	length := int(frame[0])<<16 | int(frame[1])<<8 | int(frame[2])
+	kind, flags := http2.FrameType(frame[3]), http2.Flags(frame[4])
+	frame = append(frame, make([]byte, length)...)
+	if _, err := io.ReadFull(c.Conn, frame[frameHeaderLen:]); err != nil {
+		return err
+	}
+
+	switch {
+	case kind == http2.FrameSettings && flags.Has(http2.FlagSettingsAck) && c.unacked > 0:
+		c.unacked--
+		frame = nil
+	case kind == http2.FrameSettings && !flags.Has(http2.FlagSettingsAck) && !c.requested:
+		c.unacked += c.matchers
+	}
+	if kind == http2.FrameHeaders || kind == http2.FrameContinuation {
+		c.requested = c.requested || flags.Has(http2.FlagHeadersEndHeaders)
+	}
+	c.passthrough = c.requested && c.unacked == 0
+	c.pending = frame
+
+	return nil
+}
+
+// sniff reads as much of the HTTP/2 preface as the connection opens with, any other protocol is passed through.
+func (c *settled) sniff() error {
+	preface := []byte(http2.ClientPreface)
+	buf := make([]byte, len(preface))
+	read := 0
+	for {
+		n, err := c.Conn.Read(buf[read:])
+		read += n
+		c.pending = buf[:read]
+		switch {
+		case !bytes.HasPrefix(preface, c.pending):
+			c.passthrough = true
+
+			return nil
+		case read == len(preface):
+			c.sniffed = true
+
+			return nil
+		case err != nil && read == 0:
+			return err
+		case err != nil:
+			c.passthrough = true
+
+			return nil
+		}
+	}
+}
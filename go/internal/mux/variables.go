@@ -0,0 +1,18 @@
+package mux
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// GRPCContentType identifies the requests of gRPC clients, a suffix such as +proto may follow it.
+// gRPC-Web requests share the prefix but are followed by -web so are served over HTTP.
+const GRPCContentType = "application/grpc"
+
+// Protocols offered during the TLS handshake, HTTP/1.1 first so that browsers, which offer both, call gRPC-Web
+// and Connect over HTTP/1.1 while gRPC clients, which only offer h2, negotiate HTTP/2.
+var Protocols = []string{"http/1.1", "h2"}
+
+// logger represents a configured instance of zerolog.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Logger().Output(zerolog.ConsoleWriter{Out: os.Stderr})
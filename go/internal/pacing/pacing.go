@@ -0,0 +1,152 @@
+package pacing
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Mode determines how values are spaced out on a stream.
+type Mode string
+
+const (
+	// Fixed waits the interval between each value.
+	Fixed Mode = "fixed"
+	// Burst sends values as quickly as the stream allows.
+	Burst Mode = "burst"
+	// Rate limits values to a number per second using a token bucket, allowing short bursts.
+	Rate Mode = "rate"
+	// Jitter waits the interval between each value, randomly varied by up to the jitter fraction.
+	Jitter Mode = "jitter"
+)
+
+// Modes lists the supported pacing modes.
+var Modes = []Mode{Fixed, Burst, Rate, Jitter}
+
+// Pacer determines how long to wait before sending the next value on a stream.
+type Pacer interface {
+	Delay() time.Duration
+}
+
+// Policy is the server wide pacing configuration from which each stream's Pacer is built.
+type Policy struct {
+	// Mode of pacing applied to every stream.
+	Mode Mode
+	// Interval between values for the fixed and jitter modes.
+	Interval time.Duration
+	// Rate of values per second for the rate mode.
+	Rate float64
+	// Burst is the number of values that may be sent without delay in the rate mode.
+	Burst int
+	// Jitter is the fraction, between 0 and 1, the interval may vary by in the jitter mode.
+	Jitter float64
+	// MinInterval and MaxInterval bound the interval a request may ask for.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+}
+
+// Validate checks the policy is usable.
+func (p Policy) Validate() error {
+	switch p.Mode {
+	case Fixed, Burst:
+	case Rate:
+		if p.Rate <= 0 {
+			return fmt.Errorf("%w: rate must be greater than zero", ErrPolicy)
+		}
+	case Jitter:
+		if p.Jitter < 0 || p.Jitter > 1 {
+			return fmt.Errorf("%w: jitter must be between 0 and 1", ErrPolicy)
+		}
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrPolicy, p.Mode)
+	}
+
+	if p.Interval < 0 || p.MinInterval < 0 || (p.MaxInterval > 0 && p.MaxInterval < p.MinInterval) {
+		return fmt.Errorf("%w: invalid interval bounds", ErrPolicy)
+	}
+
+	return nil
+}
+
+// bound clamps a requested interval to the limits of the policy.
+func (p Policy) bound(interval time.Duration) time.Duration {
+	if interval < p.MinInterval {
+		return p.MinInterval
+	}
+	if p.MaxInterval > 0 && interval > p.MaxInterval {
+		return p.MaxInterval
+	}
+
+	return interval
+}
+
+// Pacer builds the pacer for a stream, a non-zero requested interval overrides the policy interval,
+// or the rate in the rate mode, within the bounds of the policy.
+func (p Policy) Pacer(requested time.Duration) Pacer {
+	interval := p.Interval
+	rate := p.Rate
+	if requested > 0 {
+		interval = p.bound(requested)
+		if interval > 0 {
+			rate = float64(time.Second) / float64(interval)
+		}
+	}
+
+	switch p.Mode {
+	case Burst:
+		return fixed(0)
+	case Rate:
+		burst := float64(p.Burst)
+		if burst < 1 {
+			burst = 1
+		}
+
+		return &bucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+	case Jitter:
+		return jitter{interval: interval, jitter: p.Jitter}
+	default:
+		return fixed(interval)
+	}
+}
+
+// fixed waits the same interval before every value.
+type fixed time.Duration
+
+func (f fixed) Delay() time.Duration {
+	return time.Duration(f)
+}
+
+// jitter waits the interval randomly varied by up to the jitter fraction either way.
+type jitter struct {
+	interval time.Duration
+	jitter   float64
+}
+
+func (j jitter) Delay() time.Duration {
+	return time.Duration(float64(j.interval) * (1 + j.jitter*(2*rand.Float64()-1)))
+}
+
+// bucket is a token bucket refilled at rate tokens per second up to burst,
+// each value spends a token and waits for the bucket to recover once empty.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) Delay() time.Duration {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
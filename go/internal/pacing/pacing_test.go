@@ -0,0 +1,97 @@
+package pacing
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	invalid := []Policy{
+		{Mode: "steady"},
+		{Mode: Rate},
+		{Mode: Jitter, Jitter: 1.5},
+		{Mode: Fixed, Interval: -time.Second},
+		{Mode: Fixed, MinInterval: time.Second, MaxInterval: time.Millisecond},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, ErrPolicy) {
+			t.Errorf("%+v: expected %s, got %v", p, ErrPolicy, err)
+		}
+	}
+	for _, p := range []Policy{DefaultPolicy, {Mode: Burst}, {Mode: Rate, Rate: 10}, {Mode: Jitter, Jitter: 1}} {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%+v: %v", p, err)
+		}
+	}
+}
+
+func TestPacer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		requested time.Duration
+		delay     time.Duration
+	}{
+		{name: "fixed", policy: DefaultPolicy, delay: DefaultInterval},
+		{name: "requested", policy: DefaultPolicy, requested: 50 * time.Millisecond, delay: 50 * time.Millisecond},
+		{name: "below minimum", policy: Policy{Mode: Fixed, MinInterval: time.Second}, requested: time.Millisecond, delay: time.Second},
+		{name: "above maximum", policy: Policy{Mode: Fixed, MaxInterval: time.Second}, requested: time.Hour, delay: time.Second},
+		{name: "burst", policy: Policy{Mode: Burst, Interval: time.Second}, requested: time.Second},
+	}
+	for _, tt := range tests {
+		if delay := tt.policy.Pacer(tt.requested).Delay(); delay != tt.delay {
+			t.Errorf("%s: delayed %s want %s", tt.name, delay, tt.delay)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	t.Parallel()
+	p := Policy{Mode: Jitter, Interval: 100 * time.Millisecond, Jitter: 0.2}.Pacer(0)
+
+	// each delay varies within the jitter fraction either side of the interval
+	varied := false
+	for i := 0; i < 100; i++ {
+		delay := p.Delay()
+		if delay < 80*time.Millisecond || delay > 120*time.Millisecond {
+			t.Fatalf("delayed %s", delay)
+		}
+		varied = varied || delay != 100*time.Millisecond
+	}
+	if !varied {
+		t.Fatal("delay never varied")
+	}
+}
+
+func TestRate(t *testing.T) {
+	t.Parallel()
+	b := Policy{Mode: Rate, Rate: 10, Burst: 3}.Pacer(0).(*bucket)
+
+	// the burst is sent without delay, each value after waiting for a token to accrue at the rate
+	for i := 0; i < 3; i++ {
+		if delay := b.Delay(); delay != 0 {
+			t.Fatalf("value %d of the burst delayed %s", i, delay)
+		}
+	}
+	b.last = b.last.Add(-50 * time.Millisecond)
+	delay := b.Delay()
+	if delay < 45*time.Millisecond || delay > 50*time.Millisecond {
+		t.Fatalf("delayed %s once the burst was spent", delay)
+	}
+
+	// a requested interval sets the rate, the burst refilling no further than its size however long idle
+	b = Policy{Mode: Rate, Rate: 10}.Pacer(time.Second).(*bucket)
+	if b.rate != 1 {
+		t.Fatalf("requested interval set the rate to %v", b.rate)
+	}
+	b.last = b.last.Add(-time.Hour)
+	b.Delay()
+	if b.tokens != 0 {
+		t.Fatalf("%v tokens remain of a burst of 1", b.tokens)
+	}
+}
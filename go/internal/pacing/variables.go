@@ -0,0 +1,17 @@
+package pacing
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultInterval is the period to wait between values when not otherwise configured.
+const DefaultInterval = time.Duration(1) * time.Second
+
+var ErrPolicy = errors.New("invalid pacing policy")
+
+// DefaultPolicy waits DefaultInterval between values, requests may ask for any interval.
+var DefaultPolicy = Policy{
+	Mode:     Fixed,
+	Interval: DefaultInterval,
+}
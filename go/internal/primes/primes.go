@@ -0,0 +1,89 @@
+package primes
+
+import (
+	"exercise/internal/generator"
+	"math/big"
+)
+
+// Name the primes generator is registered under.
+const Name = "primes"
+
+// Rounds of Miller-Rabin applied in addition to the Baillie-PSW test when checking primality.
+const Rounds = 20
+
+// Generator produces the prime numbers starting at the first prime no smaller than the seed.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "the prime numbers starting at the first prime no smaller than the seed"
+}
+
+// Params lists the parameters accepted, the primes generator accepts none.
+func (Generator) Params() []generator.Param {
+	return nil
+}
+
+// Deterministic reports the sequence can be regenerated from the seed.
+func (Generator) Deterministic(map[string]string) bool {
+	return true
+}
+
+// Iterator produces the primes from the seed.
+func (Generator) Iterator(seed *big.Int, _ map[string]string) (generator.Iterator, error) {
+	return NewIterator(seed), nil
+}
+
+// Iterator produces the prime numbers by testing each candidate in turn.
+type Iterator struct {
+	seed *big.Int
+	// candidate is the next number to be tested.
+	candidate *big.Int
+}
+
+// Next returns the next prime of the sequence.
+func (it *Iterator) Next() *big.Int {
+	for !it.candidate.ProbablyPrime(Rounds) {
+		it.next()
+	}
+
+	value := new(big.Int).Set(it.candidate)
+	it.next()
+
+	return value
+}
+
+// next moves to the following candidate, skipping even numbers once past 2.
+func (it *Iterator) next() {
+	if it.candidate.Cmp(big.NewInt(2)) <= 0 {
+		it.candidate.Add(it.candidate, big.NewInt(1))
+
+		return
+	}
+
+	it.candidate.Add(it.candidate, big.NewInt(1))
+	if it.candidate.Bit(0) == 0 {
+		it.candidate.Add(it.candidate, big.NewInt(1))
+	}
+}
+
+// Reset returns the iterator to the seed.
+func (it *Iterator) Reset() {
+	it.candidate.Set(it.seed)
+}
+
+// NewIterator creates an iterator producing the primes no smaller than the seed.
+func NewIterator(seed *big.Int) *Iterator {
+	it := &Iterator{seed: new(big.Int).Set(seed), candidate: new(big.Int)}
+	if it.seed.Sign() < 0 {
+		it.seed.SetInt64(0)
+	}
+	it.Reset()
+
+	return it
+}
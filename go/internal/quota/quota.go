@@ -0,0 +1,272 @@
+package quota
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Scope of a limit, whether it bounds each client alone or all clients together.
+const (
+	Client = "client"
+	Global = "global"
+)
+
+// Resources a limit may bound.
+const (
+	Streams    = "streams"
+	Values     = "values-per-minute"
+	States     = "states"
+	StateBytes = "state-bytes"
+)
+
+// Policy bounds the resources each client, and the server as a whole, may use. A zero limit is unbounded.
+type Policy struct {
+	// ClientStreams is the number of streams a client may hold open at once.
+	ClientStreams int
+	// ClientValues is the number of values a client may be sent each Period, they may be sent at once.
+	ClientValues int64
+	// ClientStates is the number of states retained for a client, each of its client-ids retaining one.
+	ClientStates int
+	// Streams is the number of streams which may be open at once.
+	Streams int
+	// Values is the number of values which may be sent each Period, they may be sent at once.
+	Values int64
+	// States is the number of client states which may be retained.
+	States int
+	// StateBytes is the memory the retained states may hold.
+	StateBytes int64
+	// RetryAfter is how long a client is asked to wait for a stream or state to be released.
+	RetryAfter time.Duration
+}
+
+// Validate checks the policy is usable.
+func (p Policy) Validate() error {
+	if p.ClientStreams < 0 || p.ClientStates < 0 || p.Streams < 0 || p.States < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrPolicy)
+	}
+	if p.ClientValues < 0 || p.Values < 0 || p.StateBytes < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrPolicy)
+	}
+	if p.RetryAfter < 0 {
+		return fmt.Errorf("%w: retry after must not be negative", ErrPolicy)
+	}
+
+	return nil
+}
+
+// Exceeded is returned when a request would take a resource beyond its limit.
+type Exceeded struct {
+	// Scope of the limit, Client or Global.
+	Scope string
+	// Resource bounded by the limit.
+	Resource string
+	// Limit which would be exceeded.
+	Limit int64
+	// RetryAfter is how long until the resource is expected to be available.
+	RetryAfter time.Duration
+}
+
+func (e *Exceeded) Error() string {
+	return fmt.Sprintf("%s %s limit of %d exceeded, retry after %s", e.Scope, e.Resource, e.Limit, e.RetryAfter)
+}
+
+// Quotas tracks the resources used by each client against a policy.
+// Clients are identified by the subject supplied by the caller, anonymous clients should be told apart by their address.
+// Quotas are safe for concurrent use.
+type Quotas struct {
+	policy Policy
+	mu     sync.Mutex
+	// streams open for each client and in total.
+	streams     map[string]int
+	openStreams int
+	// values remaining for each client and in total.
+	values    map[string]*bucket
+	allValues *bucket
+	// owners maps the client-id of each state retained to the client it is counted against,
+	// states counts those retained for each client.
+	owners map[string]string
+	states map[string]int
+	// stateBytes held by the retained states when last measured.
+	stateBytes int64
+}
+
+// retryAfter returns how long to wait for a stream or state to be released.
+func (q *Quotas) retryAfter() time.Duration {
+	if q.policy.RetryAfter > 0 {
+		return q.policy.RetryAfter
+	}
+
+	return DefaultRetryAfter
+}
+
+// Open takes a stream for the client, release must be called once the stream ends.
+func (q *Quotas) Open(subject string) (func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if limit := q.policy.ClientStreams; limit > 0 && q.streams[subject] >= limit {
+		return nil, &Exceeded{Scope: Client, Resource: Streams, Limit: int64(limit), RetryAfter: q.retryAfter()}
+	}
+	if limit := q.policy.Streams; limit > 0 && q.openStreams >= limit {
+		return nil, &Exceeded{Scope: Global, Resource: Streams, Limit: int64(limit), RetryAfter: q.retryAfter()}
+	}
+
+	q.streams[subject]++
+	q.openStreams++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+
+			q.openStreams--
+			if q.streams[subject]--; q.streams[subject] <= 0 {
+				delete(q.streams, subject)
+			}
+		})
+	}
+
+	return release, nil
+}
+
+// bucket holds the values which may be sent, refilled continuously so that limit values are restored each Period.
+type bucket struct {
+	limit  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit int64, now time.Time) *bucket {
+	return &bucket{limit: float64(limit), tokens: float64(limit), last: now}
+}
+
+// refill restores the values accrued since last refilled, reporting whether the bucket is full.
+func (b *bucket) refill(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() / Period.Seconds() * b.limit
+	if b.tokens >= b.limit {
+		b.tokens = b.limit
+	}
+	b.last = now
+
+	return b.tokens == b.limit
+}
+
+// wait returns how long until a value may be spent.
+func (b *bucket) wait() time.Duration {
+	return time.Duration((1 - b.tokens) / b.limit * float64(Period))
+}
+
+// Spend takes a value about to be sent to the client, failing once the values available are spent.
+// Values are restored continuously, so waiting recovers a share of the limit rather than the whole Period.
+func (q *Quotas) Spend(subject string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	client := q.values[subject]
+	if limit := q.policy.ClientValues; limit > 0 {
+		if client == nil {
+			client = newBucket(limit, now)
+			q.values[subject] = client
+		}
+		if client.refill(now); client.tokens < 1 {
+			return &Exceeded{Scope: Client, Resource: Values, Limit: limit, RetryAfter: client.wait()}
+		}
+	}
+	if limit := q.policy.Values; limit > 0 {
+		if q.allValues.refill(now); q.allValues.tokens < 1 {
+			return &Exceeded{Scope: Global, Resource: Values, Limit: limit, RetryAfter: q.allValues.wait()}
+		}
+		q.allValues.tokens--
+	}
+	if client != nil {
+		client.tokens--
+	}
+
+	return nil
+}
+
+// Expire forgets the clients whose values have been fully restored, they are tracked afresh once next sent a value.
+func (q *Quotas) Expire() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for subject, b := range q.values {
+		if b.refill(now) {
+			delete(q.values, subject)
+		}
+	}
+}
+
+// Retain checks another state may be retained for the client against the client-id alongside the states already held,
+// counting it against the client. A state replacing one the client already retains for the client-id takes no more
+// room, so is neither checked nor counted again.
+func (q *Quotas) Retain(subject, clientID string, states int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if owner, owned := q.owners[clientID]; owned && owner == subject {
+		return nil
+	}
+	if limit := q.policy.ClientStates; limit > 0 && q.states[subject] >= limit {
+		return &Exceeded{Scope: Client, Resource: States, Limit: int64(limit), RetryAfter: q.retryAfter()}
+	}
+	if limit := q.policy.States; limit > 0 && states >= limit {
+		return &Exceeded{Scope: Global, Resource: States, Limit: int64(limit), RetryAfter: q.retryAfter()}
+	}
+	if limit := q.policy.StateBytes; limit > 0 && q.stateBytes >= limit {
+		return &Exceeded{Scope: Global, Resource: StateBytes, Limit: limit, RetryAfter: q.retryAfter()}
+	}
+
+	q.release(clientID)
+	q.owners[clientID] = subject
+	q.states[subject]++
+
+	return nil
+}
+
+// Release stops counting the states retained against the client-ids, once they are evicted.
+func (q *Quotas) Release(clientIDs ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, clientID := range clientIDs {
+		q.release(clientID)
+	}
+}
+
+// release stops counting the state retained against the client-id, the caller must hold the lock.
+func (q *Quotas) release(clientID string) {
+	owner, ok := q.owners[clientID]
+	if !ok {
+		return
+	}
+
+	delete(q.owners, clientID)
+	if q.states[owner]--; q.states[owner] <= 0 {
+		delete(q.states, owner)
+	}
+}
+
+// Held records the memory held by the retained states as last measured.
+func (q *Quotas) Held(bytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.stateBytes = bytes
+}
+
+// NewQuotas instantiates the tracking of resources against the policy.
+func NewQuotas(policy Policy) *Quotas {
+	return &Quotas{
+		policy:    policy,
+		streams:   map[string]int{},
+		values:    map[string]*bucket{},
+		owners:    map[string]string{},
+		states:    map[string]int{},
+		allValues: newBucket(policy.Values, time.Now()),
+	}
+}
@@ -0,0 +1,158 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// exceeds checks err is the limit of the resource in the scope being exceeded.
+func exceeds(t *testing.T, err error, scope, resource string) *Exceeded {
+	t.Helper()
+
+	var exceeded *Exceeded
+	if !errors.As(err, &exceeded) || exceeded.Scope != scope || exceeded.Resource != resource {
+		t.Fatalf("expected the %s %s limit exceeded, got %v", scope, resource, err)
+	}
+
+	return exceeded
+}
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	b := newBucket(60, start)
+
+	// a value is restored each second, so half the period restores half the limit
+	b.tokens = 0
+	if full := b.refill(start.Add(Period / 2)); full || b.tokens != 30 {
+		t.Fatalf("refilled to %v, full %t", b.tokens, full)
+	}
+	if full := b.refill(start.Add(2 * Period)); !full || b.tokens != 60 {
+		t.Fatalf("refilled beyond the limit to %v, full %t", b.tokens, full)
+	}
+
+	// the wait is for the share of a value remaining to be restored
+	b.tokens = 0.5
+	if wait := b.wait(); wait != 500*time.Millisecond {
+		t.Fatalf("waiting %s for half a value", wait)
+	}
+	b.tokens = 0
+	if wait := b.wait(); wait != time.Second {
+		t.Fatalf("waiting %s for a value", wait)
+	}
+}
+
+func TestSpend(t *testing.T) {
+	t.Parallel()
+	q := NewQuotas(Policy{ClientValues: 2, Values: 3})
+
+	for i := 0; i < 2; i++ {
+		if err := q.Spend("a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exceeded := exceeds(t, q.Spend("a"), Client, Values); exceeded.RetryAfter <= 0 || exceeded.RetryAfter > Period/2 {
+		t.Fatalf("asked to retry after %s", exceeded.RetryAfter)
+	}
+	if err := q.Spend("b"); err != nil {
+		t.Fatal(err)
+	}
+	exceeds(t, q.Spend("c"), Global, Values)
+
+	// a client refused by the global limit keeps the values it has left
+	if tokens := q.values["c"].tokens; tokens < 2 {
+		t.Fatalf("refused client left with %v values", tokens)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	t.Parallel()
+	q := NewQuotas(Policy{ClientValues: 10})
+
+	_ = q.Spend("spent")
+	_ = q.Spend("restored")
+	q.values["restored"].last = time.Now().Add(-Period)
+	q.Expire()
+
+	// only clients whose values are fully restored are forgotten
+	if _, ok := q.values["restored"]; ok {
+		t.Fatal("fully restored client still tracked")
+	}
+	if _, ok := q.values["spent"]; !ok {
+		t.Fatal("client yet to be restored forgotten")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+	q := NewQuotas(Policy{ClientStreams: 1, Streams: 2, RetryAfter: time.Minute})
+
+	release, err := q.Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exceeded := exceeds(t, refused(q.Open("a")), Client, Streams); exceeded.RetryAfter != time.Minute {
+		t.Fatalf("asked to retry after %s", exceeded.RetryAfter)
+	}
+	if _, err := q.Open("b"); err != nil {
+		t.Fatal(err)
+	}
+	exceeds(t, refused(q.Open("c")), Global, Streams)
+
+	// releasing twice frees the stream once
+	release()
+	release()
+	if _, err := q.Open("a"); err != nil {
+		t.Fatal(err)
+	}
+	exceeds(t, refused(q.Open("c")), Global, Streams)
+}
+
+// refused discards the release of a stream expected to be refused.
+func refused(_ func(), err error) error {
+	return err
+}
+
+func TestRetain(t *testing.T) {
+	t.Parallel()
+	q := NewQuotas(Policy{ClientStates: 2, States: 2, StateBytes: 100})
+
+	for i, id := range []string{"a", "b"} {
+		if err := q.Retain("client", id, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exceeds(t, q.Retain("client", "c", 2), Client, States)
+	exceeds(t, q.Retain("other", "c", 2), Global, States)
+
+	// replacing a state already retained takes no more room, so succeeds however full the server
+	q.Held(100)
+	if err := q.Retain("client", "a", 2); err != nil {
+		t.Fatalf("replacing a retained state: %v", err)
+	}
+	exceeds(t, q.Retain("other", "c", 1), Global, StateBytes)
+
+	// a client-id retained for another client is counted against the client taking it over
+	q.Held(0)
+	q.Release("b")
+	if err := q.Retain("other", "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if q.states["client"] != 0 || q.states["other"] != 1 || q.owners["a"] != "other" {
+		t.Fatalf("states counted %v owned by %v", q.states, q.owners)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []Policy{{Streams: -1}, {ClientValues: -1}, {StateBytes: -1}, {RetryAfter: -time.Second}} {
+		if err := p.Validate(); !errors.Is(err, ErrPolicy) {
+			t.Errorf("%+v: expected %s, got %v", p, ErrPolicy, err)
+		}
+	}
+	if err := DefaultPolicy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,17 @@
+package quota
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultRetryAfter is how long clients are asked to wait when no better estimate of when a limit frees up is known.
+const DefaultRetryAfter = time.Duration(1) * time.Second
+
+// Period is the time over which the values a client may be sent are restored.
+const Period = time.Minute
+
+var ErrPolicy = errors.New("invalid quota policy")
+
+// DefaultPolicy leaves every resource unbounded.
+var DefaultPolicy = Policy{RetryAfter: DefaultRetryAfter}
@@ -0,0 +1,239 @@
+package random
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"exercise/internal/generator"
+	"math"
+	"math/big"
+	mrand "math/rand"
+)
+
+// Distributions values may be drawn from.
+const (
+	Uniform     = "uniform"
+	Normal      = "normal"
+	Exponential = "exponential"
+	Poisson     = "poisson"
+	Zipf        = "zipf"
+)
+
+// PoissonThreshold is the mean above which Poisson values are approximated from the normal distribution.
+const PoissonThreshold = 30
+
+// Distributions lists the distributions values may be drawn from.
+var Distributions = []string{Uniform, Normal, Exponential, Poisson, Zipf}
+
+// Distribution draws a value from the stream generated for a single position of the sequence.
+type Distribution func(stream *Stream) *big.Int
+
+// Stream is the unbounded keystream generated for a single position of the sequence,
+// read directly for values of any size or through Rand for floating point distributions.
+type Stream struct {
+	cipher.Stream
+	Rand *mrand.Rand
+}
+
+// Read fills p from the keystream, implementing io.Reader.
+func (s *Stream) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	s.XORKeyStream(p, p)
+
+	return len(p), nil
+}
+
+// Uint64 reads the next 64 bits of the keystream, implementing math/rand.Source64.
+func (s *Stream) Uint64() uint64 {
+	var buf [8]byte
+	_, _ = s.Read(buf[:])
+
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+// Int63 reads the next 63 bits of the keystream, implementing math/rand.Source.
+func (s *Stream) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed is a no-op, the keystream is determined by the key and position, implementing math/rand.Source.
+func (s *Stream) Seed(int64) {}
+
+// newStream creates the keystream for position.
+func newStream(block cipher.Block, position int64) *Stream {
+	iv := make([]byte, block.BlockSize())
+	binary.BigEndian.PutUint64(iv, uint64(position))
+
+	s := &Stream{Stream: cipher.NewCTR(block, iv)}
+	s.Rand = mrand.New(s)
+
+	return s
+}
+
+// bounded draws values uniformly from [min, max).
+func bounded(min, max *big.Int) Distribution {
+	span := new(big.Int).Sub(max, min)
+
+	return func(stream *Stream) *big.Int {
+		value, err := rand.Int(stream, span)
+		if err != nil {
+			return new(big.Int).Set(min)
+		}
+
+		return value.Add(value, min)
+	}
+}
+
+// bound clamps a draw which is not finite, having overflowed, to the end of [min, max) it exceeds.
+func bound(x float64, min, max *big.Int) (*big.Int, bool) {
+	switch {
+	case math.IsInf(x, 1):
+		return new(big.Int).Sub(max, big.NewInt(1)), true
+	case math.IsInf(x, -1) || math.IsNaN(x):
+		return new(big.Int).Set(min), true
+	default:
+		return nil, false
+	}
+}
+
+// clamped rounds x to the nearest integer within [min, max).
+func clamped(x float64, min, max *big.Int) *big.Int {
+	if value, ok := bound(x, min, max); ok {
+		return value
+	}
+
+	value, _ := new(big.Float).SetFloat64(math.Round(x)).Int(nil)
+	if value.Cmp(min) < 0 {
+		return value.Set(min)
+	}
+	if value.Cmp(max) >= 0 {
+		return value.Sub(max, big.NewInt(1))
+	}
+
+	return value
+}
+
+// offset adds x to min, clamped within [min, max).
+func offset(x float64, min, max *big.Int) *big.Int {
+	if value, ok := bound(x, min, max); ok {
+		return value
+	}
+
+	f := new(big.Float).SetInt(min)
+	f.Add(f, big.NewFloat(x))
+	value, _ := f.Int(nil)
+	if value.Cmp(max) >= 0 {
+		return value.Sub(max, big.NewInt(1))
+	}
+
+	return value
+}
+
+// poisson draws the number of events occurring with mean lambda, multiplying uniform values until
+// they fall below e^-lambda, approximated from the normal distribution above PoissonThreshold.
+func poisson(r *mrand.Rand, lambda float64) float64 {
+	if lambda > PoissonThreshold {
+		return math.Max(0, math.Round(lambda+math.Sqrt(lambda)*r.NormFloat64()))
+	}
+
+	limit := math.Exp(-lambda)
+	k, p := 0.0, r.Float64()
+	for p > limit {
+		k++
+		p *= r.Float64()
+	}
+
+	return k
+}
+
+// positive parses the named parameter as a number greater than zero.
+func positive(params map[string]string, name string) (float64, error) {
+	value, err := generator.Float(params, name)
+	if err == nil && value <= 0 {
+		err = generator.Invalid(name, "must be greater than zero")
+	}
+
+	return value, err
+}
+
+// NewDistribution builds the distribution described by the parameters, values are bounded by [min, max).
+func NewDistribution(params map[string]string) (Distribution, error) {
+	min, err := generator.Int(params, "min")
+	if err != nil {
+		return nil, err
+	}
+	max, err := generator.Int(params, "max")
+	if err != nil {
+		return nil, err
+	}
+	if min.Sign() < 0 || max.Cmp(min) <= 0 {
+		return nil, generator.Invalid("max", "must be greater than min and min must not be negative")
+	}
+
+	switch params["distribution"] {
+	case Uniform:
+		return bounded(min, max), nil
+	case Normal:
+		mean, err := generator.Float(params, "mean")
+		if err != nil {
+			return nil, err
+		}
+		stddev, err := positive(params, "stddev")
+		if err != nil {
+			return nil, err
+		}
+
+		return func(stream *Stream) *big.Int {
+			return clamped(mean+stddev*stream.Rand.NormFloat64(), min, max)
+		}, nil
+	case Exponential:
+		rate, err := positive(params, "rate")
+		if err != nil {
+			return nil, err
+		}
+
+		return func(stream *Stream) *big.Int {
+			return offset(math.Floor(stream.Rand.ExpFloat64()/rate), min, max)
+		}, nil
+	case Poisson:
+		lambda, err := positive(params, "lambda")
+		if err != nil {
+			return nil, err
+		}
+
+		return func(stream *Stream) *big.Int {
+			return offset(poisson(stream.Rand, lambda), min, max)
+		}, nil
+	case Zipf:
+		s, err := generator.Float(params, "s")
+		if err != nil {
+			return nil, err
+		}
+		v, err := generator.Float(params, "v")
+		if err != nil {
+			return nil, err
+		}
+		if s <= 1 {
+			return nil, generator.Invalid("s", "must be greater than 1")
+		}
+		if v < 1 {
+			return nil, generator.Invalid("v", "must be at least 1")
+		}
+
+		// the zipf distribution draws from [0, imax], limited to the range of a uint64
+		imax := uint64(math.MaxUint64)
+		if span := new(big.Int).Sub(max, min); span.IsUint64() {
+			imax = span.Uint64() - 1
+		}
+
+		return func(stream *Stream) *big.Int {
+			value := new(big.Int).SetUint64(mrand.NewZipf(stream.Rand, s, v, imax).Uint64())
+
+			return value.Add(value, min)
+		}, nil
+	default:
+		return nil, generator.Invalid("distribution", "must be one of %v not %q", Distributions, params["distribution"])
+	}
+}
@@ -0,0 +1,65 @@
+package random
+
+import (
+	"exercise/internal/generator"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestDistributionOverflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+	}{
+		{name: "exponential tiny rate", params: map[string]string{"distribution": Exponential, "rate": "1e-320"}},
+		{name: "normal huge mean", params: map[string]string{"distribution": Normal, "mean": "1e308", "stddev": "1e308"}},
+		{name: "normal huge negative mean", params: map[string]string{"distribution": Normal, "mean": "-1e308", "stddev": "1e308"}},
+		{name: "poisson huge lambda", params: map[string]string{"distribution": Poisson, "lambda": "1e308"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := generator.Validate(Generator{}, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			it, err := Generator{}.Iterator(big.NewInt(1), params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			min, max := big.NewInt(0), big.NewInt(MaxValue)
+			for i := 0; i < 100; i++ {
+				value := it.Next()
+				if value.Cmp(min) < 0 || value.Cmp(max) >= 0 {
+					t.Fatalf("value %d of %s is outside [%s, %s)", i, value, min, max)
+				}
+			}
+		})
+	}
+}
+
+func TestBound(t *testing.T) {
+	min, max := big.NewInt(10), big.NewInt(20)
+	tests := []struct {
+		x    float64
+		want int64
+	}{
+		{x: math.Inf(1), want: 19},
+		{x: math.Inf(-1), want: 10},
+		{x: math.NaN(), want: 10},
+		{x: 1e300, want: 19},
+		{x: 15.4, want: 15},
+	}
+
+	for _, tt := range tests {
+		if got := clamped(tt.x, min, max); got.Int64() != tt.want {
+			t.Errorf("clamped(%v) = %s, want %d", tt.x, got, tt.want)
+		}
+		if got := offset(tt.x-10, min, max); got.Int64() != tt.want {
+			t.Errorf("offset(%v) = %s, want %d", tt.x-10, got, tt.want)
+		}
+	}
+}
@@ -1,33 +1,119 @@
 package random
 
 import (
-	"crypto/rand"
-	"exercise/internal/state"
-	v1 "exercise/pkg/ably/v1"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"exercise/internal/generator"
+	"fmt"
 	"math/big"
+	"strconv"
+)
+
+const (
+	// Name the random generator is registered under.
+	Name = "random"
+
+	// ModeSecret keys the sequence with a seed chosen by the server and never revealed.
+	ModeSecret = "secret"
+
+	// ModeSeeded keys the sequence with the request seed so it can be regenerated by anyone holding the seed.
+	ModeSeeded = "seeded"
 )
 
 const MaxValue = int64(^uint32(0))
 
-// GetSequence generates a sequence of values and pushes them to the supplied channel.
-func GetSequence(qty int64, _ *big.Int) ([]*big.Int, error) {
-	seq := make([]*big.Int, qty+1)
+// Generator produces a sequence of random values.
+type Generator struct{}
+
+// Name uniquely identifies the generator.
+func (Generator) Name() string {
+	return Name
+}
+
+// Description summarises the sequence generated.
+func (Generator) Description() string {
+	return "a sequence of random values, uniform below 0xffffffff by default, resuming requires a client-id unless seeded"
+}
 
-	for cntr := int64(0); cntr < qty+1; cntr++ {
-		r, err := rand.Int(rand.Reader, big.NewInt(MaxValue))
-		if err != nil {
-			return nil, err
-		}
-		seq[cntr] = r
+// Params lists the parameters accepted.
+func (Generator) Params() []generator.Param {
+	return []generator.Param{
+		{
+			Name:        "mode",
+			Description: "secret keys the sequence with a seed known only to the server, seeded keys it with the request seed, which must then be given, so it can be regenerated",
+			Default:     ModeSecret,
+		},
+		{Name: "distribution", Description: fmt.Sprintf("the distribution values are drawn from, one of %v", Distributions), Default: Uniform},
+		{Name: "min", Description: "the smallest value, inclusive and of any size", Default: "0"},
+		{Name: "max", Description: "the largest value, exclusive and of any size, values beyond the bounds are clamped", Default: strconv.FormatInt(MaxValue, 10)},
+		{Name: "mean", Description: "the mean of the normal distribution", Default: "1000"},
+		{Name: "stddev", Description: "the standard deviation of the normal distribution", Default: "100"},
+		{Name: "rate", Description: "the rate of the exponential distribution above min", Default: "0.001"},
+		{Name: "lambda", Description: "the mean of the poisson distribution above min", Default: "10"},
+		{Name: "s", Description: "the exponent of the zipf distribution above min, greater than 1", Default: "1.1"},
+		{Name: "v", Description: "the offset of the zipf distribution, at least 1", Default: "1"},
 	}
+}
+
+// Deterministic reports whether the sequence can be regenerated by the client, only when seeded by the request,
+// otherwise the seed keying the sequence is chosen by the server and never revealed.
+func (Generator) Deterministic(params map[string]string) bool {
+	return params["mode"] == ModeSeeded
+}
 
-	return seq, nil
+// RequiresSeed reports the request must carry the seed when seeded, the values never revealing the seed keying them.
+func (Generator) RequiresSeed(params map[string]string) bool {
+	return params["mode"] == ModeSeeded
 }
 
-// GetRequest build a suitable v1.DoublerRequest from the provided state.Stateful
-func GetRequest(s state.Stateful) *v1.Request {
-	return &v1.Request{
-		Qty:  s.Require(),
-		Seed: s.Last().Int64(),
+// Iterator produces the random values keyed by the seed and drawn from the distribution described by the parameters.
+func (Generator) Iterator(seed *big.Int, params map[string]string) (generator.Iterator, error) {
+	if mode := params["mode"]; mode != ModeSecret && mode != ModeSeeded {
+		return nil, generator.Invalid("mode", "must be %s or %s not %q", ModeSecret, ModeSeeded, mode)
 	}
+
+	dist, err := NewDistribution(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIterator(seed, dist)
+}
+
+// Iterator produces a sequence of random values from a keystream generated by encrypting the position of each
+// value under a key derived from the seed, so any position of the sequence can be regenerated without those before it.
+type Iterator struct {
+	block    cipher.Block
+	dist     Distribution
+	position int64
+}
+
+// Next returns the next value of the sequence.
+func (it *Iterator) Next() *big.Int {
+	value := it.dist(newStream(it.block, it.position))
+	it.position++
+
+	return value
+}
+
+// Reset returns the iterator to the start of the sequence.
+func (it *Iterator) Reset() {
+	it.position = 0
+}
+
+// SeekTo moves the iterator to position.
+func (it *Iterator) SeekTo(position int64) {
+	it.position = position
+}
+
+// NewIterator creates an iterator producing the random values keyed by the seed and drawn from dist.
+func NewIterator(seed *big.Int, dist Distribution) (*Iterator, error) {
+	key := sha256.Sum256(seed.Bytes())
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Iterator{block: block, dist: dist}, nil
 }
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/pacing"
+	"exercise/internal/store"
+	"exercise/internal/token"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// Paced spaces out the values so a stream is still sending when drained.
+var Paced = WithPacing(pacing.Policy{Mode: pacing.Fixed, Interval: 10 * time.Millisecond})
+
+// goingAway reads the values of the stream until it ends, failing unless its final response is going away.
+// The values received, and the token the response says to resume from with its position, are returned.
+func goingAway(t *testing.T, stream interface {
+	Recv() (*v1.Response, error)
+}) ([]*big.Int, []byte, int64) {
+	t.Helper()
+
+	var values []*big.Int
+	var last *v1.Response
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last != nil && last.GoingAway {
+			t.Fatalf("response at %d sent after going away", res.Sequence)
+		}
+		if !res.GoingAway {
+			values = append(values, new(big.Int).SetBytes(res.Value))
+		}
+		last = res
+	}
+	if last == nil || !last.GoingAway || last.Token == nil {
+		t.Fatalf("stream ended without going away: %+v", last)
+	}
+	resume, err := token.Decode(last.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values, last.Token, resume.Position
+}
+
+func TestDrainGoesAway(t *testing.T) {
+	t.Parallel()
+	shared := store.NewMemory(time.Hour)
+	client, svc := serve(t, nil, Paced, WithStore(shared))
+	ctx := withClientID(context.Background(), "drained")
+
+	req := &v1.Request{Qty: 100, Seed: 1}
+	stream, err := client.Doubler(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := receive(stream, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.Drain()
+	svc.Drain()
+
+	// the stream stops after the value being sent, telling the client to resume after it
+	rest, resume, position := goingAway(t, stream)
+	received := int64(len(head.values) + len(rest))
+	if position != received || received >= req.Qty {
+		t.Fatalf("told to resume from %d having received %d values", position, received)
+	}
+
+	// new streams are refused while draining
+	refused, err := client.Doubler(ctx, &v1.Request{Qty: 1, Seed: 1})
+	if err == nil {
+		_, err = receive(refused, 0)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected %s once draining, got %v", codes.Unavailable, err)
+	}
+
+	// another server sharing the state resumes the stream without repeating or skipping a value
+	other, _ := serve(t, nil, WithStore(shared))
+	req.Token = resume
+	tail := double(ctx, t, other, req)
+	total := sum(append(append(head.values, rest...), tail.values...)...)
+	if received+int64(len(tail.values)) != req.Qty || total.Cmp(tail.checksum) != 0 {
+		t.Fatalf("received %d+%d values totalling %s, checksum %s", received, len(tail.values), total, tail.checksum)
+	}
+}
+
+func TestDrainSubscriptionResumesFromCommitted(t *testing.T) {
+	t.Parallel()
+	client, svc := serve(t, nil, Paced)
+
+	sub := subscribe(withClientID(context.Background(), "drained"), t, client, &v1.Request{Qty: 100, Seed: 1}, 4)
+	sub.values(t, 0, 4)
+	sub.ack(t, 1)
+	sub.values(t, 4, 2)
+	svc.Drain()
+
+	// values sent but not acknowledged are resent on resume, so the client resumes after the last acknowledged
+	for {
+		res := sub.next(t)
+		if !res.GoingAway {
+			continue
+		}
+		resume, err := token.Decode(res.Token)
+		if err != nil || resume.Position != 2 {
+			t.Fatalf("told to resume from %d: %v", resume.Position, err)
+		}
+
+		break
+	}
+	if err := <-sub.err; !errors.Is(err, io.EOF) {
+		t.Fatalf("stream ended with %v", err)
+	}
+}
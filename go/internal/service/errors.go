@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/generator"
+	"exercise/internal/quota"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// violation describes why a field of the request was not accepted, formatted as per fmt.Sprintf.
+func violation(field, format string, args ...interface{}) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)}
+}
+
+// paramViolation converts an error from a generator into a violation of the parameter it concerns.
+func paramViolation(err error) *errdetails.BadRequest_FieldViolation {
+	var paramErr *generator.ParamError
+	if !errors.As(err, &paramErr) {
+		return violation("params", err.Error())
+	}
+
+	if paramErr.Name == generator.Seed {
+		return violation("seed", paramErr.Reason)
+	}
+
+	return violation("params."+paramErr.Name, paramErr.Reason)
+}
+
+// invalid builds an InvalidArgument status carrying the violations as BadRequest details.
+func invalid(violations ...*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.GetField() + " " + v.GetDescription()
+	}
+
+	st := status.New(codes.InvalidArgument, strings.Join(descriptions, ", "))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+// exhausted builds a ResourceExhausted status carrying the limit exceeded by subject as QuotaFailure details.
+func exhausted(subject, format string, args ...interface{}) error {
+	description := fmt.Sprintf(format, args...)
+	st := status.New(codes.ResourceExhausted, subject+" "+description)
+	detailed, err := st.WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: subject, Description: description}},
+	})
+	if err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+// limited builds a ResourceExhausted status for a quota the stream would exceed, carrying how long to wait as
+// RetryInfo details and in the retry-after trailer, in whole seconds, for clients which do not read the details.
+func limited(ctx context.Context, err error) error {
+	var exceeded *quota.Exceeded
+	if !errors.As(err, &exceeded) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	seconds := int64(math.Ceil(exceeded.RetryAfter.Seconds()))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterKey, strconv.FormatInt(seconds, 10)))
+
+	st := status.New(codes.ResourceExhausted, exceeded.Error())
+	detailed, detailErr := st.WithDetails(
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     exceeded.Scope + ":" + exceeded.Resource,
+			Description: fmt.Sprintf("limit of %d exceeded", exceeded.Limit),
+		}}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(exceeded.RetryAfter)},
+	)
+	if detailErr == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
+
+// validate checks the request is within the bounds of the service before anything is generated.
+func validate(req *v1.Request) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.GetQty() <= 0 {
+		violations = append(violations, violation("qty", "must be greater than zero"))
+	}
+	if req.GetSeed() < 0 || req.GetSeed() > MaxSeed {
+		violations = append(violations, violation("seed", "must be between 0 and %d", MaxSeed))
+	}
+	if req.GetLast() != 0 && len(req.GetToken()) == 0 {
+		violations = append(violations, violation("last", "is only checked when resuming from a token"))
+	}
+	if _, ok := v1.Integrity_name[int32(req.GetIntegrity())]; !ok {
+		violations = append(violations, violation("integrity", "%d is not a supported mode", req.GetIntegrity()))
+	}
+	if len(violations) > 0 {
+		return invalid(violations...)
+	}
+
+	if req.GetQty() > MaxQty {
+		return exhausted("qty", "must not exceed %d values", MaxQty)
+	}
+
+	return nil
+}
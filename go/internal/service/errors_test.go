@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/quota"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// violated returns the fields the BadRequest details of err say were violated, in order.
+func violated(t *testing.T, err error) []string {
+	t.Helper()
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected %s, got %v", codes.InvalidArgument, err)
+	}
+	var fields []string
+	for _, detail := range st.Details() {
+		if bad, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range bad.FieldViolations {
+				if v.Description == "" {
+					t.Fatalf("%s violated without a description", v.Field)
+				}
+				fields = append(fields, v.Field)
+			}
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// quotaFailure returns the subject of the QuotaFailure details of err and how long RetryInfo asks to wait.
+func quotaFailure(t *testing.T, err error) (string, time.Duration) {
+	t.Helper()
+
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("expected %s, got %v", codes.ResourceExhausted, err)
+	}
+	var subject string
+	var retryAfter time.Duration
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.QuotaFailure:
+			subject = d.Violations[0].Subject
+		case *errdetails.RetryInfo:
+			retryAfter = d.RetryDelay.AsDuration()
+		}
+	}
+
+	return subject, retryAfter
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		req    *v1.Request
+		fields []string
+	}{
+		{req: &v1.Request{Qty: 1, Seed: MaxSeed}},
+		{req: &v1.Request{Qty: 0, Seed: -1}, fields: []string{"qty", "seed"}},
+		{req: &v1.Request{Qty: 1, Seed: MaxSeed + 1, Last: 1}, fields: []string{"last", "seed"}},
+		{req: &v1.Request{Qty: 1, Integrity: v1.Integrity(99)}, fields: []string{"integrity"}},
+	}
+	for _, tt := range tests {
+		err := validate(tt.req)
+		if tt.fields == nil {
+			if err != nil {
+				t.Errorf("%+v: %v", tt.req, err)
+			}
+
+			continue
+		}
+		if fields := violated(t, err); strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%+v: violated %v want %v", tt.req, fields, tt.fields)
+		}
+	}
+
+	// a valid request for more values than are ever sent exceeds a limit rather than being invalid
+	if subject, _ := quotaFailure(t, validate(&v1.Request{Qty: MaxQty + 1})); subject != "qty" {
+		t.Fatalf("exceeded the limit of %s", subject)
+	}
+}
+
+func TestInvalidRequestDetails(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+
+	tests := []struct {
+		req    *v1.GenerateRequest
+		fields []string
+	}{
+		{req: &v1.GenerateRequest{Generator: "unknown", Request: &v1.Request{Qty: 1}}, fields: []string{"generator"}},
+		{req: &v1.GenerateRequest{Generator: "doubler", Request: &v1.Request{Qty: 1, Token: []byte{0}}}, fields: []string{"token"}},
+		{req: &v1.GenerateRequest{Generator: "arithmetic", Request: &v1.Request{Qty: 1, Seed: 1, Params: map[string]string{"step": "x"}}}, fields: []string{"params.step"}},
+	}
+	for _, tt := range tests {
+		stream, err := client.Generate(context.Background(), tt.req)
+		if err == nil {
+			_, err = receive(stream, 0)
+		}
+		if fields := violated(t, err); strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s: violated %v want %v", tt.req.Generator, fields, tt.fields)
+		}
+	}
+}
+
+func TestLimitedDetails(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil, Paced, WithQuotas(quota.Policy{ClientStreams: 1, RetryAfter: 1500 * time.Millisecond}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	held, err := client.Doubler(ctx, &v1.Request{Qty: MaxQty, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := receive(held, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// the quota exceeded and how long to wait are detailed, the wait rounded up to whole seconds in the trailer
+	var trailer metadata.MD
+	stream, err := client.Doubler(context.Background(), &v1.Request{Qty: 1, Seed: 1}, grpc.Trailer(&trailer))
+	if err == nil {
+		_, err = receive(stream, 0)
+	}
+	subject, retryAfter := quotaFailure(t, err)
+	if subject != quota.Client+":"+quota.Streams || retryAfter != 1500*time.Millisecond {
+		t.Fatalf("exceeded %s, asked to retry after %s", subject, retryAfter)
+	}
+	if seconds := trailer.Get(RetryAfterKey); len(seconds) != 1 || seconds[0] != "2" {
+		t.Fatalf("trailer asked to retry after %v seconds", seconds)
+	}
+
+	// anything but a quota being exceeded is an internal failure
+	if code := status.Code(limited(context.Background(), errors.New("store unavailable"))); code != codes.Internal {
+		t.Fatalf("failed with %s", code)
+	}
+}
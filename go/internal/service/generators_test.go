@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/generator"
+	"math"
+	"math/big"
+	"testing"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// number parses a decimal value of any size.
+func number(t *testing.T, value string) *big.Int {
+	t.Helper()
+
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		t.Fatalf("invalid number %q", value)
+	}
+
+	return n
+}
+
+// fib returns the nth Fibonacci number by iteration.
+func fib(n int) *big.Int {
+	a, b := big.NewInt(0), big.NewInt(1)
+	for i := 0; i < n; i++ {
+		a.Add(a, b)
+		a, b = b, a
+	}
+
+	return a
+}
+
+// iterator builds the named generator's iterator from the registry, applying defaults for omitted parameters.
+func iterator(name string, seed *big.Int, params map[string]string) (generator.Iterator, error) {
+	g, err := DefaultRegistry().Get(name)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := generator.Validate(g, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Iterator(seed, resolved)
+}
+
+func TestGenerators(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		generator string
+		seed      *big.Int
+		params    map[string]string
+		// prefix are the first values of the sequence.
+		prefix []string
+		// at are the values at positions beyond the prefix, reached by skipping.
+		at map[int64]string
+	}{
+		{
+			generator: "doubler",
+			seed:      big.NewInt(3),
+			prefix:    []string{"3", "6", "12", "24", "48"},
+			at:        map[int64]string{100: new(big.Int).Lsh(big.NewInt(3), 100).String()},
+		},
+		{
+			generator: "arithmetic",
+			seed:      big.NewInt(5),
+			params:    map[string]string{"step": "3"},
+			prefix:    []string{"5", "8", "11", "14", "17"},
+			at:        map[int64]string{1 << 40: big.NewInt(5 + 3<<40).String()},
+		},
+		{
+			generator: "arithmetic",
+			seed:      big.NewInt(math.MaxInt64 - 1),
+			prefix:    []string{"9223372036854775806", "9223372036854775807", "9223372036854775808", "9223372036854775809"},
+		},
+		{
+			generator: "geometric",
+			seed:      big.NewInt(2),
+			params:    map[string]string{"multiplier": "3/2"},
+			prefix:    []string{"2", "3", "4", "6", "10", "15"},
+		},
+		{
+			generator: "geometric",
+			seed:      big.NewInt(100),
+			params:    map[string]string{"multiplier": "0.5"},
+			prefix:    []string{"100", "50", "25", "12", "6", "3", "1", "0"},
+		},
+		{
+			generator: "fibonacci",
+			seed:      big.NewInt(0),
+			prefix:    []string{"0", "1", "1", "2", "3", "5", "8", "13"},
+			at:        map[int64]string{100: "354224848179261915075", 1000: fib(1000).String()},
+		},
+		{
+			generator: "fibonacci",
+			seed:      big.NewInt(10),
+			prefix:    []string{"55", "89", "144"},
+		},
+		{
+			generator: "lucas",
+			seed:      big.NewInt(0),
+			prefix:    []string{"2", "1", "3", "4", "7", "11", "18"},
+			at:        map[int64]string{100: new(big.Int).Add(fib(99), fib(101)).String()},
+		},
+		{
+			generator: "factorial",
+			seed:      big.NewInt(0),
+			prefix:    []string{"1", "1", "2", "6", "24", "120"},
+			at:        map[int64]string{25: "15511210043330985984000000", 1000: new(big.Int).MulRange(1, 1000).String()},
+		},
+		{
+			generator: "primes",
+			seed:      big.NewInt(0),
+			prefix:    []string{"2", "3", "5", "7", "11", "13"},
+			at:        map[int64]string{999: "7919", 1000: "7927"},
+		},
+		{
+			generator: "primes",
+			seed:      big.NewInt(90),
+			prefix:    []string{"97", "101", "103"},
+		},
+		{
+			generator: "collatz",
+			seed:      big.NewInt(6),
+			prefix:    []string{"6", "3", "10", "5", "16", "8", "4", "2", "1", "4"},
+		},
+		{
+			generator: "collatz",
+			seed:      big.NewInt(27),
+			prefix:    []string{"27", "82", "41", "124"},
+			at:        map[int64]string{77: "9232", 111: "1"},
+		},
+	}
+	for _, tt := range tests {
+		it, err := iterator(tt.generator, tt.seed, tt.params)
+		if err != nil {
+			t.Fatalf("%s seed=%s: %s", tt.generator, tt.seed, err)
+		}
+
+		for i, want := range tt.prefix {
+			if got := it.Next(); got.Cmp(number(t, want)) != 0 {
+				t.Fatalf("%s seed=%s: value %d is %s want %s", tt.generator, tt.seed, i, got, want)
+			}
+		}
+
+		// skipping to each position continues the sequence as generating the values before it would
+		for i, want := range tt.prefix {
+			generator.Skip(it, int64(i))
+			if got := it.Next(); got.Cmp(number(t, want)) != 0 {
+				t.Fatalf("%s seed=%s: skipping to %d got %s want %s", tt.generator, tt.seed, i, got, want)
+			}
+		}
+
+		for position, want := range tt.at {
+			generator.Skip(it, position)
+			if got := it.Next(); got.Cmp(number(t, want)) != 0 {
+				t.Fatalf("%s seed=%s: skipping to %d got %s want %s", tt.generator, tt.seed, position, got, want)
+			}
+		}
+
+		it.Reset()
+		if got := it.Next(); got.Cmp(number(t, tt.prefix[0])) != 0 {
+			t.Fatalf("%s seed=%s: reset to %s want %s", tt.generator, tt.seed, got, tt.prefix[0])
+		}
+	}
+}
+
+func TestGeneratorsRejectInvalidParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		generator string
+		seed      *big.Int
+		params    map[string]string
+	}{
+		{generator: "doubler", seed: big.NewInt(1), params: map[string]string{"step": "1"}},
+		{generator: "random", seed: big.NewInt(1), params: map[string]string{"mode": "guessable"}},
+		{generator: "arithmetic", seed: big.NewInt(1), params: map[string]string{"step": "-1"}},
+		{generator: "arithmetic", seed: big.NewInt(1), params: map[string]string{"step": "1.5"}},
+		{generator: "geometric", seed: big.NewInt(1), params: map[string]string{"multiplier": "x"}},
+		{generator: "geometric", seed: big.NewInt(1), params: map[string]string{"multiplier": "-3/2"}},
+		{generator: "geometric", seed: big.NewInt(1), params: map[string]string{"multiplier": "1/0"}},
+		{generator: "fibonacci", seed: big.NewInt(-1)},
+		{generator: "lucas", seed: new(big.Int).Lsh(big.NewInt(1), 64)},
+		{generator: "factorial", seed: big.NewInt(-1)},
+	}
+	for _, tt := range tests {
+		if _, err := iterator(tt.generator, tt.seed, tt.params); !errors.Is(err, generator.ErrParam) {
+			t.Errorf("%s seed=%s params=%v: expected %s, got %v", tt.generator, tt.seed, tt.params, generator.ErrParam, err)
+		}
+	}
+}
+
+func TestRegistryListsEveryGenerator(t *testing.T) {
+	t.Parallel()
+
+	want := []string{"arithmetic", "collatz", "doubler", "factorial", "fibonacci", "geometric", "lucas", "primes", "random"}
+	list := DefaultRegistry().List()
+	if len(list) != len(want) {
+		t.Fatalf("registry lists %d generators want %d", len(list), len(want))
+	}
+	for i, g := range list {
+		if g.Name() != want[i] {
+			t.Fatalf("generator %d is %s want %s", i, g.Name(), want[i])
+		}
+	}
+
+	if _, err := DefaultRegistry().Get("unknown"); err == nil {
+		t.Fatal("unknown generator found")
+	}
+}
+
+func TestListGenerators(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+
+	res, err := client.ListGenerators(context.Background(), &v1.ListGeneratorsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered := DefaultRegistry().List()
+	if len(res.Generators) != len(registered) {
+		t.Fatalf("listed %d of %d generators", len(res.Generators), len(registered))
+	}
+
+	// each generator is described with its parameters, whether deterministic as given their defaults
+	for i, info := range res.Generators {
+		g := registered[i]
+		if info.Name != g.Name() || info.Description == "" || len(info.Params) != len(g.Params()) {
+			t.Fatalf("generator %d listed as %s with %d params", i, info.Name, len(info.Params))
+		}
+		for j, p := range info.Params {
+			if want := g.Params()[j]; p.Name != want.Name || p.Description != want.Description || p.Default != want.Default {
+				t.Fatalf("%s: param %d listed as %+v", info.Name, j, p)
+			}
+		}
+		if deterministic := info.Name != "random"; info.Deterministic != deterministic {
+			t.Fatalf("%s: listed deterministic %t", info.Name, info.Deterministic)
+		}
+	}
+
+	// only the generators registered are listed
+	registry, err := generator.NewRegistry(DefaultRegistry().List()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ = serve(t, nil, WithRegistry(registry))
+	if res, err := client.ListGenerators(context.Background(), &v1.ListGeneratorsRequest{}); err != nil || len(res.Generators) != 1 {
+		t.Fatalf("listed %d generators: %v", len(res.GetGenerators()), err)
+	}
+}
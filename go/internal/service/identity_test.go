@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"exercise/internal/auth"
+	"math/big"
+	"testing"
+
+	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// identify builds the context of a request from a client presenting a certificate for subject, if not empty,
+// and sending the client-id, if not empty.
+func identify(subject, id string) context.Context {
+	ctx := context.Background()
+	if subject != "" {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: subject}}
+		state := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+	if id != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("client-id", id))
+	}
+
+	return ctx
+}
+
+func TestClientIDNamespacesCertificates(t *testing.T) {
+	t.Parallel()
+
+	holder := clientID(identify("alice", "foo"))
+	if holder != "cert:alice/id:foo" {
+		t.Fatalf("certificate holder identified as %q", holder)
+	}
+
+	// clients without a certificate cannot craft the identity of a certificate holder
+	for _, id := range []string{"alice/foo", "cert:alice/id:foo", "alice/id:foo", "cert:alice/foo"} {
+		if got := clientID(identify("", id)); got == holder {
+			t.Errorf("client-id %q identified as the certificate holder %q", id, got)
+		}
+	}
+
+	// nor can a certificate subject span the client-id
+	if got := clientID(identify("alice/id:foo", "")); got == holder {
+		t.Errorf("certificate subject identified as %q", got)
+	}
+}
+
+// tenant identifies the client of the context as authenticated for the tenant.
+func tenant(ctx context.Context, name string) context.Context {
+	return auth.WithTenant(ctx, name)
+}
+
+func TestClientIDNamespacesTenants(t *testing.T) {
+	t.Parallel()
+
+	acme := clientID(tenant(identify("", "shared"), "acme"))
+	if acme != "tenant:acme/id:shared" {
+		t.Fatalf("tenant identified as %q", acme)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "other tenant", ctx: tenant(identify("", "shared"), "globex")},
+		{name: "anonymous", ctx: identify("", "shared")},
+		{name: "crafted client-id", ctx: identify("", "acme/shared")},
+		{name: "crafted namespaced client-id", ctx: identify("", "tenant:acme/id:shared")},
+		{name: "crafted tenant", ctx: tenant(identify("", "id:shared"), "acme/")},
+		{name: "tenant spanning client-id", ctx: tenant(context.Background(), "acme/id:shared")},
+		{name: "certificate named as the tenant", ctx: identify("acme", "shared")},
+		{name: "certificate spanning tenant", ctx: identify("tenant:acme", "shared")},
+	}
+	for _, tt := range tests {
+		if got := clientID(tt.ctx); got == acme {
+			t.Errorf("%s identified as the tenant %q", tt.name, got)
+		}
+	}
+}
+
+func TestTenantsIsolated(t *testing.T) {
+	t.Parallel()
+
+	// the tenant is taken from the tenant metadata the test sends in place of a token
+	authenticate := func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		wrapped := grpcMiddleware.WrapServerStream(ss)
+		if names := md.Get("tenant"); len(names) > 0 {
+			wrapped.WrappedContext = auth.WithTenant(ss.Context(), names[0])
+		}
+
+		return handler(srv, wrapped)
+	}
+	client, _ := serve(t, []grpc.StreamServerInterceptor{authenticate})
+	as := func(name string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "client-id", "shared", "tenant", name)
+	}
+
+	ctx, cancel := context.WithCancel(as("acme"))
+	stream, err := client.Random(ctx, &v1.Request{Qty: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := receive(stream, 3)
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resume := &v1.Request{Qty: 10, Token: head.tokens[len(head.tokens)-1]}
+
+	// the random sequence can only be resumed from the state retained for the client, which another tenant cannot reach
+	stream, err = client.Random(as("globex"), resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := receive(stream, 0); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("another tenant resumed the stream: %v", err)
+	}
+
+	stream, err = client.Random(as("acme"), resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail, err := receive(stream, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := new(big.Int).Add(head.total(), tail.total())
+	if len(head.values)+len(tail.values) != 10 || total.Cmp(tail.checksum) != 0 {
+		t.Fatalf("received %d+%d values totalling %s, checksum %s", len(head.values), len(tail.values), total, tail.checksum)
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrSuperseded is returned to a stream when a newer connection for the same client takes over its state.
+var ErrSuperseded = status.Error(codes.Aborted, "stream superseded by a newer connection for the client")
+
+// lease grants a single stream exclusive use of a client's state.
+type lease struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// leases tracks the stream currently holding each client's state.
+type leases struct {
+	mu     sync.Mutex
+	active map[string]*lease
+}
+
+// acquire grants the caller exclusive use of the client's state, superseding any stream that currently holds it.
+// The returned context is cancelled if the lease is superseded and release must be called once the stream ends.
+// Anonymous clients have no retained state so are granted a lease without contention.
+func (l *leases) acquire(ctx context.Context, clientID string) (context.Context, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+	if clientID == "" {
+		return ctx, cancel, nil
+	}
+
+	ls := &lease{cancel: cancel, done: make(chan struct{})}
+
+	l.mu.Lock()
+	prev := l.active[clientID]
+	l.active[clientID] = ls
+	l.mu.Unlock()
+
+	release := func() {
+		l.mu.Lock()
+		if l.active[clientID] == ls {
+			delete(l.active, clientID)
+		}
+		l.mu.Unlock()
+		cancel()
+		close(ls.done)
+	}
+
+	if prev != nil {
+		prev.cancel()
+		<-prev.done
+	}
+
+	// a newer stream may have superseded this one while waiting
+	if ctx.Err() != nil {
+		release()
+
+		return nil, nil, ErrSuperseded
+	}
+
+	return ctx, release, nil
+}
+
+// interrupted returns the error to close a stream with once its lease context is done,
+// distinguishing the client going away from the lease being superseded.
+func interrupted(stream context.Context) error {
+	if stream.Err() != nil {
+		return status.FromContextError(stream.Err()).Err()
+	}
+
+	return ErrSuperseded
+}
+
+func newLeases() *leases {
+	return &leases{active: map[string]*lease{}}
+}
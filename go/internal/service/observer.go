@@ -0,0 +1,25 @@
+package service
+
+import (
+	"time"
+)
+
+// Observer receives notifications of notable events within the service, typically to record metrics.
+type Observer interface {
+	// ValueSent is called after each value is sent on a stream with the time taken to send it.
+	ValueSent(method string, latency time.Duration)
+	// Resumed is called when a stream is served from a stored state.
+	Resumed(method string)
+	// Evicted is called with the number of states evicted after exceeding their TTL.
+	Evicted(count int)
+	// StatesHeld is called periodically with the number of states held in the store.
+	StatesHeld(count int)
+}
+
+// nopObserver discards all notifications.
+type nopObserver struct{}
+
+func (nopObserver) ValueSent(string, time.Duration) {}
+func (nopObserver) Resumed(string)                  {}
+func (nopObserver) Evicted(int)                     {}
+func (nopObserver) StatesHeld(int)                  {}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"exercise/internal/generator"
+	"exercise/internal/pacing"
+	"exercise/internal/quota"
+	"exercise/internal/store"
+
+	"github.com/rs/zerolog"
+)
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithStore sets the store used to retain client states between requests.
+func WithStore(st store.Store) Option {
+	return func(s *Service) {
+		s.store = st
+	}
+}
+
+// WithObserver sets the observer notified of events within the service.
+func WithObserver(o Observer) Option {
+	return func(s *Service) {
+		s.observer = o
+	}
+}
+
+// WithPacing sets the policy determining how values are spaced out on each stream.
+func WithPacing(policy pacing.Policy) Option {
+	return func(s *Service) {
+		s.pacing = policy
+	}
+}
+
+// WithQuotas sets the policy bounding the resources each client, and the server as a whole, may use.
+func WithQuotas(policy quota.Policy) Option {
+	return func(s *Service) {
+		s.quotas = quota.NewQuotas(policy)
+	}
+}
+
+// WithLogger sets the logger the service reports to, stderr by default.
+func WithLogger(l zerolog.Logger) Option {
+	return func(s *Service) {
+		s.logger = l
+	}
+}
+
+// WithRegistry sets the registry of generators the service can stream.
+func WithRegistry(r *generator.Registry) Option {
+	return func(s *Service) {
+		s.registry = r
+	}
+}
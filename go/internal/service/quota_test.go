@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"exercise/internal/quota"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// exhausts checks the doubler sequence requested for the client-id is refused as exceeding a quota.
+func exhausts(t *testing.T, client v1.ServiceClient, clientID string, req *v1.Request) {
+	t.Helper()
+
+	stream, err := client.Doubler(withClientID(context.Background(), clientID), req)
+	if err == nil {
+		_, err = receive(stream, 0)
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("client-id %q: expected %s, got %v", clientID, codes.ResourceExhausted, err)
+	}
+}
+
+func TestQuotasNotEscapedByClientID(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil, WithQuotas(quota.Policy{ClientValues: 5}))
+
+	r := double(withClientID(context.Background(), "first"), t, client, &v1.Request{Qty: 5, Seed: 1})
+	if len(r.values) != 5 {
+		t.Fatalf("received %d values", len(r.values))
+	}
+
+	// the values are counted against the peer, so a fresh client-id has none remaining
+	exhausts(t, client, "second", &v1.Request{Qty: 1, Seed: 1})
+}
+
+func TestClientStatesBounded(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil, WithQuotas(quota.Policy{ClientStates: 2}))
+
+	for _, id := range []string{"a", "b"} {
+		double(withClientID(context.Background(), id), t, client, &v1.Request{Qty: 2, Seed: 1})
+	}
+	exhausts(t, client, "c", &v1.Request{Qty: 2, Seed: 1})
+
+	// replacing a state already retained for the client does not count again
+	r := double(withClientID(context.Background(), "a"), t, client, &v1.Request{Qty: 3, Seed: 2})
+	if len(r.values) != 3 {
+		t.Fatalf("received %d values replacing a retained state", len(r.values))
+	}
+}
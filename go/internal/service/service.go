@@ -3,23 +3,67 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"exercise/internal/arithmetic"
+	"exercise/internal/auth"
+	"exercise/internal/certs"
+	"exercise/internal/collatz"
 	"exercise/internal/doubler"
+	"exercise/internal/factorial"
+	"exercise/internal/fibonacci"
+	"exercise/internal/generator"
+	"exercise/internal/geometric"
+	"exercise/internal/integrity"
+	"exercise/internal/pacing"
+	"exercise/internal/primes"
+	"exercise/internal/quota"
 	"exercise/internal/random"
 	"exercise/internal/state"
+	"exercise/internal/store"
+	"exercise/internal/token"
+	"io"
 	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	v1 "exercise/pkg/ably/v1"
 )
 
+// sender is satisfied by the server side of each of the streaming rpcs.
+type sender interface {
+	Send(*v1.Response) error
+	Context() context.Context
+}
+
 type Service struct {
 	v1.UnimplementedServiceServer
-	state map[string]*state.State
+	store    store.Store
+	registry *generator.Registry
+	leases   *leases
+	observer Observer
+	pacing   pacing.Policy
+	quotas   *quota.Quotas
+	drain    sync.Once
+	draining chan struct{}
+	logger   zerolog.Logger
+}
+
+// secret returns a random seed of SecretBits for a sequence the client must not be able to regenerate.
+func (s *Service) secret() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), SecretBits))
 }
 
-// seed returns the seed if greater than zero otherwise returns a random integer between 0 and MaxSeed.
+// seed returns the seed if greater than zero otherwise returns a random integer between 1 and MaxSeed,
+// a seed of zero would leave sequences such as the doubler and collatz stuck at zero.
 func (s *Service) seed(seed int64) *big.Int {
 	if seed > 0 {
 		return big.NewInt(seed)
@@ -29,101 +73,549 @@ func (s *Service) seed(seed int64) *big.Int {
 
 	r, err := rand.Int(rand.Reader, big.NewInt(max))
 	if err != nil {
-		logger.Error().Err(err)
+		s.logger.Error().Err(err).Msg("Unable to choose a seed")
+
+		return big.NewInt(1)
 	}
 
-	return r
+	return r.Add(r, big.NewInt(1))
 }
 
-// getState retrieves/instantiates a state object for a request.
-// if a client_id is supplied in metadata then the state is persisted in memory
-func (s *Service) getState(ctx context.Context, qty int64, seq []*big.Int) *state.State {
+// requiresSeed reports whether the generator only regenerates its sequence from a seed the client chose.
+func requiresSeed(g generator.Generator, params map[string]string) bool {
+	seeded, ok := g.(generator.Seeded)
+
+	return ok && seeded.RequiresSeed(params)
+}
+
+// namespace builds the part of a client's identity of the kind, the value is escaped so that it cannot be mistaken
+// for a part of another kind nor span parts.
+func namespace(kind, value string) string {
+	return kind + ":" + url.PathEscape(value)
+}
+
+// identity returns the parts identifying an authenticated client, the subject of a verified client certificate
+// and the tenant of an authenticated token, empty for anonymous clients.
+func identity(ctx context.Context) []string {
+	var parts []string
+	if subject, ok := certs.Identity(ctx); ok {
+		parts = append(parts, namespace(CertNamespace, subject))
+	}
+	if tenant, ok := auth.Tenant(ctx); ok {
+		parts = append(parts, namespace(TenantNamespace, tenant))
+	}
+
+	return parts
+}
+
+// clientID returns the identity the client's state is retained against, empty for anonymous clients.
+// The subject of a verified client certificate and the tenant of an authenticated token identify the client,
+// any client-id supplied in the request metadata is then namespaced beneath them so certificate holders
+// and tenants cannot access each other's states.
+func clientID(ctx context.Context) string {
+	parts := identity(ctx)
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if clientID, ok := md["client-id"]; ok {
-			if state, ok := s.state[clientID[0]]; ok {
-				return state
-			}
-			s.state[clientID[0]] = state.NewState(qty, seq)
+		if clientID, ok := md["client-id"]; ok && len(clientID) > 0 && clientID[0] != "" {
+			parts = append(parts, namespace(IDNamespace, clientID[0]))
+		}
+	}
 
-			return s.state[clientID[0]]
+	return strings.Join(parts, "/")
+}
+
+// subject identifies the client quotas are tracked against by the certificate and tenant it is authenticated as,
+// anonymous clients are told apart by their address. The client-id is chosen freely by the client so is not
+// part of the subject, otherwise rotating it would escape the quotas.
+func subject(ctx context.Context) string {
+	if parts := identity(ctx); len(parts) > 0 {
+		return strings.Join(parts, "/")
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return namespace(PeerNamespace, host)
 		}
+
+		return namespace(PeerNamespace, p.Addr.String())
 	}
 
-	return state.NewState(qty, seq)
+	return ""
 }
 
-// Doubler handles the incoming request and pushes values into the return stream.
-func (s *Service) Doubler(req *v1.Request, stream v1.Service_DoublerServer) error {
-	seq, _ := doubler.GetSequence(req.GetQty(), big.NewInt(req.GetSeed()))
-	state := s.getState(stream.Context(), req.GetQty(), seq)
+// getState retrieves/instantiates a state object for a request.
+// if a client_id is supplied then the state is retained in the store, the caller must hold the client's lease.
+// The retained state is only resumed from by a request carrying a resume token for the same quantity, generator,
+// seed and parameters, otherwise it is replaced provided the state quotas allow. A new state is generated from seed,
+// as the source of a sequence whose seed is kept secret carries none to match against.
+// The returned bool reports whether the state was already held for the client.
+func (s *Service) getState(clientID, subject string, qty int64, src *state.Source, seed *big.Int, resuming bool) (*state.State, bool, error) {
+	fresh := &state.Source{Generator: src.Generator, Seed: seed, Params: src.Params}
+	if clientID == "" {
+		return state.NewState(qty, fresh), false, nil
+	}
 
-	for {
-		if !state.Next() {
-			break
+	st, ok := s.store.Load(clientID)
+	if ok && resuming && st.Quantity() == qty && st.Source().Matches(src) {
+		return st, true, nil
+	}
+
+	// a state replacing the client's own is not counted against the states retained
+	held := s.store.Len()
+	if ok {
+		held--
+	}
+	if err := s.quotas.Retain(subject, clientID, held); err != nil {
+		return nil, false, err
+	}
+
+	st = state.NewState(qty, fresh)
+	s.save(clientID, st)
+
+	return st, false, nil
+}
+
+// save records progress against the client's state, anonymous states are not retained.
+func (s *Service) save(clientID string, st *state.State) {
+	if clientID == "" {
+		return
+	}
+
+	if err := s.store.Save(clientID, st); err != nil {
+		s.logger.Error().Err(err).Str("client-id", clientID).Msg("Unable to save state")
+	}
+}
+
+// resume moves the state cursor to the position identified by the session's resume token, if one was supplied.
+// Should the client say which value it saw last, it must be the value before the position resumed from.
+func (s *Service) resume(sess *session) error {
+	if sess.resume == nil {
+		return nil
+	}
+
+	position := sess.resume.Position
+	if _, err := sess.state.Seek(position, io.SeekStart); err != nil {
+		return status.Errorf(codes.OutOfRange, "unable to resume at position %d: %s", position, err)
+	}
+	if last := sess.state.Last(); sess.last != 0 && position > 0 && last != nil && last.Cmp(big.NewInt(sess.last)) != 0 {
+		return status.Errorf(codes.FailedPrecondition, "last value %d differs from %s at position %d", sess.last, last, position-1)
+	}
+	sess.partial = true
+
+	return nil
+}
+
+// value builds the response carrying the value at the cursor, the first value sent once resumed
+// also carries the total of the values before it so the client can verify its tally.
+func (sess *session) value() (*v1.Response, error) {
+	st := sess.state
+	position := st.Position()
+	current := st.Current()
+	res := &v1.Response{
+		Value:    current.Bytes(),
+		Sequence: position,
+		Token:    sess.resumeToken(position + 1),
+	}
+	if sess.chained {
+		res.Chain = integrity.Link(st.Chain(), position, current)
+	}
+
+	if sess.partial {
+		total, err := st.TotalAt(position)
+		if err != nil {
+			return nil, err
 		}
-		err := stream.Send(&v1.Response{Value: state.Current().Bytes()})
+		res.Total = total.Bytes()
+		sess.partial = false
+	}
+
+	return res, nil
+}
+
+// checksum builds the final response carrying the sum of the sequence and, if negotiated, the head of its chain.
+func (sess *session) checksum() *v1.Response {
+	res := &v1.Response{Checksum: sess.state.Total().Bytes()}
+	if sess.chained {
+		res.Chain = sess.state.Chain()
+	}
+
+	return res
+}
+
+// session describes a single stream of values being sent to a client.
+type session struct {
+	// method is the name of the rpc serving the stream.
+	method string
+	// clientID the state is retained against, empty for anonymous clients.
+	clientID string
+	// subject the quotas of the client are tracked against.
+	subject string
+	// state being streamed from.
+	state *state.State
+	// stored reports the state was retained for the client before the session opened.
+	stored bool
+	// pacer spacing values out on the stream.
+	pacer pacing.Pacer
+	// seed the sequence was generated from.
+	seed int64
+	// resume is the decoded resume token supplied by the client, nil when starting afresh.
+	resume *token.Token
+	// last value the client saw before the position resumed from, zero when not supplied.
+	last int64
+	// partial reports the next value should carry the total of the values before it, set once resumed.
+	partial bool
+	// chained reports each value should carry the head of the hash chain, as negotiated by the client.
+	chained bool
+}
+
+// resumeToken builds the resume token for position within the session's sequence.
+func (sess *session) resumeToken(position int64) []byte {
+	return token.Token{Position: position, Seed: sess.seed}.Encode()
+}
+
+// send pushes the values from the current cursor position onwards into the stream,
+// each carrying its position and a token to resume from, followed by the checksum.
+func (s *Service) send(ctx context.Context, stream sender, sess *session) error {
+	st := sess.state
+	for ok := st.Position() < st.Quantity(); ok; ok = st.Next() {
+		res, err := sess.value()
 		if err != nil {
-			logger.Error().Err(err)
+			return status.Error(codes.Internal, err.Error())
 		}
-		time.Sleep(interval)
+		if err := s.quotas.Spend(sess.subject); err != nil {
+			return limited(stream.Context(), err)
+		}
+		start := time.Now()
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+		s.observer.ValueSent(sess.method, time.Since(start))
+		s.save(sess.clientID, st)
+
+		select {
+		case <-ctx.Done():
+			return interrupted(stream.Context())
+		case <-s.draining:
+			return s.goAway(stream, sess, res.Sequence+1)
+		case <-time.After(sess.pacer.Delay()):
+		}
+	}
+	return stream.Send(sess.checksum())
+}
+
+// pacer builds the pacer for a request from the service pacing policy.
+func (s *Service) pacer(req *v1.Request) pacing.Pacer {
+	return s.pacing.Pacer(time.Duration(req.GetIntervalMs()) * time.Millisecond)
+}
+
+// goAway notifies the client the server is shutting down with a token to resume from position.
+func (s *Service) goAway(stream sender, sess *session, position int64) error {
+	return stream.Send(&v1.Response{
+		Token:     sess.resumeToken(position),
+		GoingAway: true,
+	})
+}
+
+// available rejects new streams once the service has started draining.
+func (s *Service) available() error {
+	select {
+	case <-s.draining:
+		return status.Error(codes.Unavailable, "server is shutting down")
+	default:
+		return nil
 	}
-	err := stream.Send(&v1.Response{Checksum: state.Total().Bytes()})
+}
+
+// Drain asks all active streams to stop, each sends a final going away response
+// carrying the position to resume from, new streams are rejected.
+func (s *Service) Drain() {
+	s.drain.Do(func() { close(s.draining) })
+}
+
+// generator looks up the named generator, resolving the parameters requested against those it accepts.
+func (s *Service) generator(name string, params map[string]string) (generator.Generator, map[string]string, error) {
+	g, err := s.registry.Get(name)
 	if err != nil {
-		logger.Error().Err(err)
+		return nil, nil, invalid(violation("generator", "%q is not registered", name))
 	}
-	stream.Context().Done()
 
-	return nil
+	resolved, err := generator.Validate(g, params)
+	if err != nil {
+		return nil, nil, invalid(paramViolation(err))
+	}
+
+	return g, resolved, nil
 }
 
-func (s *Service) Random(req *v1.Request, stream v1.Service_RandomServer) error {
-	seq, err := random.GetSequence(req.GetQty(), big.NewInt(0))
+// open prepares a session streaming the named generator, taking the lease on the client's state.
+// The returned context is cancelled should the lease be superseded and release must be called once the stream ends.
+// A sequence which cannot be regenerated can only be resumed from a retained state.
+func (s *Service) open(ctx context.Context, method, name string, req *v1.Request) (context.Context, *session, func(), error) {
+	if err := s.available(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := validate(req); err != nil {
+		return nil, nil, nil, err
+	}
+
+	g, params, err := s.generator(name, req.GetParams())
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
-	state := s.getState(stream.Context(), req.GetQty(), seq)
 
-	for {
-		err := stream.Send(&v1.Response{Value: state.Current().Bytes()})
+	sess := &session{
+		method:   method,
+		clientID: clientID(ctx),
+		subject:  subject(ctx),
+		pacer:    s.pacer(req),
+		chained:  req.GetIntegrity() == v1.Integrity_INTEGRITY_CHAIN,
+		last:     req.GetLast(),
+	}
+
+	if len(req.GetToken()) > 0 {
+		tok, err := token.Decode(req.GetToken())
 		if err != nil {
-			logger.Error().Err(err)
+			return nil, nil, nil, invalid(violation("token", err.Error()))
 		}
-		if !state.Next() {
-			break
+		sess.resume = &tok
+	}
+
+	// a deterministic sequence is regenerated from the seed carried by the resume token,
+	// the seed of any other sequence is kept secret so only the state retained can resume it
+	deterministic := g.Deterministic(params)
+	var seed *big.Int
+	switch {
+	case !deterministic:
+		if seed, err = s.secret(); err != nil {
+			return nil, nil, nil, status.Error(codes.Internal, err.Error())
 		}
-		time.Sleep(interval)
+	case sess.resume != nil:
+		seed = big.NewInt(sess.resume.Seed)
+	case req.GetSeed() == 0 && requiresSeed(g, params):
+		return nil, nil, nil, invalid(violation("seed", "must be chosen by the client to regenerate the %s sequence", name))
+	default:
+		seed = s.seed(req.GetSeed())
+	}
+	// a retained state is matched against the seed unless it is kept secret
+	src := &state.Source{Generator: name, Params: params}
+	if deterministic {
+		src.Seed = seed
 	}
-	err = stream.Send(&v1.Response{Checksum: state.Total().Bytes()})
+
+	// the iterator is built up front so invalid parameters are rejected before any state is retained
+	iter, err := g.Iterator(seed, src.Params)
 	if err != nil {
-		logger.Error().Err(err)
+		return nil, nil, nil, invalid(paramViolation(err))
 	}
-	stream.Context().Done()
 
-	return nil
+	ctx, release, err := s.leases.acquire(ctx, sess.clientID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sess.state, sess.stored, err = s.getState(sess.clientID, sess.subject, req.GetQty(), src, seed, sess.resume != nil)
+	if err != nil {
+		release()
+
+		return nil, nil, nil, limited(ctx, err)
+	}
+	if sess.resume != nil && !sess.stored && !deterministic {
+		release()
+
+		return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "no state retained to resume the %s sequence from", name)
+	}
+	if sess.stored {
+		s.observer.Resumed(method)
+	}
+	src = sess.state.Source()
+
+	// states restored from the store only describe their source, the iterator is rebuilt from it
+	if !sess.state.Attached() {
+		if sess.stored {
+			if iter, err = g.Iterator(src.Seed, src.Params); err != nil {
+				release()
+
+				return nil, nil, nil, invalid(paramViolation(err))
+			}
+		}
+		sess.state.Attach(iter)
+	}
+	if deterministic {
+		sess.seed = src.Seed.Int64()
+	}
+
+	return ctx, sess, release, nil
+}
+
+// stream serves a request for the named generator, resuming from the token supplied if any.
+func (s *Service) stream(method, name string, req *v1.Request, stream sender) error {
+	ctx, sess, release, err := s.open(stream.Context(), method, name, req)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := s.resume(sess); err != nil {
+		return err
+	}
+
+	return s.send(ctx, stream, sess)
+}
+
+// StreamServerInterceptor limits the streams of the service each client, and the server as a whole, may hold open at once.
+// Streams of other services, such as health watches, are not limited.
+func (s *Service) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	prefix := "/" + v1.Service_ServiceDesc.ServiceName + "/"
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(srv, ss)
+		}
+
+		release, err := s.quotas.Open(subject(ss.Context()))
+		if err != nil {
+			return limited(ss.Context(), err)
+		}
+		defer release()
+
+		return handler(srv, ss)
+	}
+}
+
+// Doubler handles the incoming request and pushes values into the return stream.
+// The sequence is deterministic so a resume token can be honoured whether or not state was retained.
+func (s *Service) Doubler(req *v1.Request, stream v1.Service_DoublerServer) error {
+	return s.stream("Doubler", doubler.Name, req, stream)
+}
+
+// Random handles the incoming request and pushes values into the return stream.
+// The sequence cannot be regenerated so resuming requires the state retained for the client.
+func (s *Service) Random(req *v1.Request, stream v1.Service_RandomServer) error {
+	return s.stream("Random", random.Name, req, stream)
+}
+
+// Generate handles a request for any registered generator and pushes values into the return stream.
+func (s *Service) Generate(req *v1.GenerateRequest, stream v1.Service_GenerateServer) error {
+	return s.stream("Generate", req.GetGenerator(), req.GetRequest(), stream)
+}
+
+// ListGenerators describes each of the registered generators and the parameters they accept.
+func (s *Service) ListGenerators(context.Context, *v1.ListGeneratorsRequest) (*v1.ListGeneratorsResponse, error) {
+	res := &v1.ListGeneratorsResponse{}
+	for _, g := range s.registry.List() {
+		defaults, err := generator.Validate(g, nil)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		info := &v1.GeneratorInfo{
+			Name:          g.Name(),
+			Description:   g.Description(),
+			Deterministic: g.Deterministic(defaults),
+		}
+		for _, p := range g.Params() {
+			info.Params = append(info.Params, &v1.Param{
+				Name:        p.Name,
+				Description: p.Description,
+				Default:     p.Default,
+			})
+		}
+		res.Generators = append(res.Generators, info)
+	}
+
+	return res, nil
 }
 
-// MaintainStates provides a convenience method to clean up stale states.
-func (s *Service) MaintainStates() {
+// MaintainStates provides a convenience method to evict stale states and flush changes to the store
+// until the context is done.
+func (s *Service) MaintainStates(ctx context.Context) {
 	for {
-		time.Sleep(time.Duration(1) * time.Second)
-		now := time.Now()
-		for id, st := range s.state {
-			if now.Sub(st.Accessed()).Seconds() > StateTTL {
-				delete(s.state, id)
-			} else {
-				logger.Debug().
-					Str("client-id", id).
-					Int64("position", st.Position()).
-					Float64("ttl", StateTTL-now.Sub(st.Accessed()).Seconds()).
-					Send()
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(1) * time.Second):
+		}
+
+		evicted, err := s.store.Expire()
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Unable to evict stale states")
+		}
+		for _, id := range evicted {
+			s.logger.Debug().Str("client-id", id).Msg("Evicted state")
 		}
+		s.quotas.Release(evicted...)
+		s.observer.Evicted(len(evicted))
+		s.observer.StatesHeld(s.store.Len())
+		if err := s.store.Flush(); err != nil {
+			s.logger.Error().Err(err).Msg("Unable to flush states")
+		}
+
+		now := time.Now()
+		var held int64
+		s.store.Range(func(id string, st *state.State) bool {
+			held += st.Size()
+			s.logger.Debug().
+				Str("client-id", id).
+				Int64("position", st.Position()).
+				Float64("idle", now.Sub(st.Accessed()).Seconds()).
+				Send()
+
+			return true
+		})
+		s.quotas.Held(held)
+		s.quotas.Expire()
+	}
+}
+
+// Close flushes and closes the underlying store.
+func (s *Service) Close() error {
+	return s.store.Close()
+}
+
+// DefaultRegistry creates a registry holding the generators built into the service.
+func DefaultRegistry() *generator.Registry {
+	r, err := generator.NewRegistry(
+		doubler.Generator{},
+		random.Generator{},
+		geometric.Generator{},
+		arithmetic.Generator{},
+		fibonacci.Generator{},
+		fibonacci.Lucas{},
+		factorial.Generator{},
+		primes.Generator{},
+		collatz.Generator{},
+	)
+	if err != nil {
+		panic(err)
 	}
+
+	return r
 }
 
-// NewService instantiates a new service container.
-func NewService() *Service {
-	return &Service{
-		state: map[string]*state.State{},
+// NewService instantiates a new service container, by default states are held in memory for StateTTL seconds.
+func NewService(opts ...Option) *Service {
+	s := &Service{
+		leases:   newLeases(),
+		draining: make(chan struct{}),
+		pacing:   pacing.DefaultPolicy,
+		logger:   logger,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	if s.observer == nil {
+		s.observer = nopObserver{}
+	}
+	if s.store == nil {
+		s.store = store.NewMemory(StateTTL * time.Second)
+	}
+	if s.registry == nil {
+		s.registry = DefaultRegistry()
+	}
+	if s.quotas == nil {
+		s.quotas = quota.NewQuotas(quota.DefaultPolicy)
+	}
+
+	return s
 }
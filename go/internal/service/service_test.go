@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/pacing"
+	"exercise/internal/random"
+	"exercise/internal/store"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// serve starts the service on an in-memory listener with the interceptors, returning a client of it.
+// Values are sent without delay unless the options say otherwise, everything is stopped once the test ends.
+func serve(t *testing.T, interceptors []grpc.StreamServerInterceptor, opts ...Option) (v1.ServiceClient, *Service) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	svc := NewService(append([]Option{WithPacing(pacing.Policy{Mode: pacing.Burst})}, opts...)...)
+	srv := grpc.NewServer(grpc.ChainStreamInterceptor(append(interceptors, svc.StreamServerInterceptor())...))
+	v1.RegisterServiceServer(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+
+	dialer := func(context.Context, string) (net.Conn, error) { return lis.Dial() }
+	conn, err := grpc.Dial("bufconn", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		srv.Stop()
+		_ = svc.Close()
+	})
+
+	return v1.NewServiceClient(conn), svc
+}
+
+// withClientID sends the client-id with every request made with the context.
+func withClientID(ctx context.Context, clientID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "client-id", clientID)
+}
+
+// received is what a client received from a stream.
+type received struct {
+	values   []*big.Int
+	tokens   [][]byte
+	checksum *big.Int
+}
+
+// total sums the values received.
+func (r received) total() *big.Int {
+	total := new(big.Int)
+	for _, v := range r.values {
+		total.Add(total, v)
+	}
+
+	return total
+}
+
+// receive reads the stream until it ends, or limit values have been received when greater than zero.
+func receive(stream interface {
+	Recv() (*v1.Response, error)
+}, limit int) (received, error) {
+	var r received
+	for limit <= 0 || len(r.values) < limit {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return r, nil
+		}
+		if err != nil {
+			return r, err
+		}
+		if res.Token == nil {
+			r.checksum = new(big.Int).SetBytes(res.Checksum)
+
+			continue
+		}
+		r.values = append(r.values, new(big.Int).SetBytes(res.Value))
+		r.tokens = append(r.tokens, res.Token)
+	}
+
+	return r, nil
+}
+
+// double streams the doubler sequence requested to completion.
+func double(ctx context.Context, t *testing.T, client v1.ServiceClient, req *v1.Request) received {
+	t.Helper()
+
+	stream, err := client.Doubler(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := receive(stream, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestRetainedStateOnlyResumedWithToken(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+	ctx := withClientID(context.Background(), "same")
+
+	first := double(ctx, t, client, &v1.Request{Qty: 5, Seed: 1})
+	if len(first.values) != 5 || first.total().Cmp(first.checksum) != 0 {
+		t.Fatalf("first stream received %d values totalling %s, checksum %s", len(first.values), first.total(), first.checksum)
+	}
+
+	// a new request for the same client-id without a token replaces the retained state
+	tests := []*v1.Request{
+		{Qty: 3, Seed: 2},
+		{Qty: 5, Seed: 1},
+		{Qty: 4, Seed: 1},
+	}
+	for _, req := range tests {
+		r := double(ctx, t, client, req)
+		if int64(len(r.values)) != req.Qty || r.total().Cmp(r.checksum) != 0 {
+			t.Fatalf("qty=%d seed=%d received %d values totalling %s, checksum %s", req.Qty, req.Seed, len(r.values), r.total(), r.checksum)
+		}
+	}
+}
+
+func TestResumeFromToken(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+
+	for _, name := range []string{"doubler", "random"} {
+		ctx, cancel := context.WithCancel(withClientID(context.Background(), "resume-"+name))
+		req := &v1.Request{Qty: 10, Seed: 3}
+		stream, err := client.Generate(ctx, &v1.GenerateRequest{Generator: name, Request: req})
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := receive(stream, 4)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Token = head.tokens[len(head.tokens)-1]
+		stream, err = client.Generate(withClientID(context.Background(), "resume-"+name), &v1.GenerateRequest{Generator: name, Request: req})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tail, err := receive(stream, 0)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		total := new(big.Int).Add(head.total(), tail.total())
+		if len(head.values)+len(tail.values) != 10 || total.Cmp(tail.checksum) != 0 {
+			t.Fatalf("%s: received %d+%d values totalling %s, checksum %s", name, len(head.values), len(tail.values), total, tail.checksum)
+		}
+	}
+}
+
+func TestSeededRandomRequiresSeed(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+	params := map[string]string{"mode": random.ModeSeeded}
+
+	stream, err := client.Generate(context.Background(), &v1.GenerateRequest{Generator: random.Name, Request: &v1.Request{Qty: 3, Params: params}})
+	if err == nil {
+		_, err = receive(stream, 0)
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("seeded without a seed expected %s, got %v", codes.InvalidArgument, err)
+	}
+
+	stream, err = client.Generate(context.Background(), &v1.GenerateRequest{Generator: random.Name, Request: &v1.Request{Qty: 3, Seed: 9, Params: params}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := receive(stream, 0); err != nil || len(r.values) != 3 {
+		t.Fatalf("seeded with a seed received %d values, %v", len(r.values), err)
+	}
+}
+
+func TestSeedChosenAboveZero(t *testing.T) {
+	t.Parallel()
+	svc := NewService()
+
+	for i := 0; i < 4*MaxSeed; i++ {
+		if seed := svc.seed(0); seed.Sign() <= 0 || seed.Cmp(big.NewInt(MaxSeed)) > 0 {
+			t.Fatalf("chose seed %s", seed)
+		}
+	}
+	if seed := svc.seed(7); seed.Int64() != 7 {
+		t.Fatalf("seed 7 replaced by %s", seed)
+	}
+}
+
+func TestStatesFlushedEachSecond(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "states.db")
+	st, err := store.NewFile(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, svc := serve(t, nil, WithStore(st))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.MaintainStates(ctx)
+
+	double(withClientID(ctx, "flushed"), t, client, &v1.Request{Qty: 3, Seed: 1})
+
+	// the state reaches disk without the store being closed, as a copy of the database taken then shows
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied := filepath.Join(t.TempDir(), "copy.db")
+		if err := os.WriteFile(copied, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := store.NewFile(copied, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held := restored.Len()
+		_ = restored.Close()
+		if held == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("state not flushed")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestResumeAfterRestart(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "states.db")
+	ctx := withClientID(context.Background(), "restarted")
+
+	st, err := store.NewFile(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, svc := serve(t, nil, WithStore(st))
+	streamCtx, cancel := context.WithCancel(ctx)
+	req := &v1.Request{Qty: 10, Seed: 3}
+	stream, err := client.Doubler(streamCtx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := receive(stream, 4)
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// once the cancelled stream has ended, closing the service flushes its state for the next service to open
+	time.Sleep(Quiet)
+	if err := svc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err = store.NewFile(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _ = serve(t, nil, WithStore(st))
+	req.Token = head.tokens[len(head.tokens)-1]
+	stream, err = client.Doubler(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail, err := receive(stream, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := new(big.Int).Add(head.total(), tail.total())
+	if len(head.values)+len(tail.values) != 10 || total.Cmp(tail.checksum) != 0 {
+		t.Fatalf("received %d+%d values totalling %s, checksum %s", len(head.values), len(tail.values), total, tail.checksum)
+	}
+}
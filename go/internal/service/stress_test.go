@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/pacing"
+	"exercise/internal/store"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// StressClients is the number of clients each stress test runs concurrently, fewer in short mode.
+const StressClients = 100
+
+// scenario describes the behaviour of a single simulated client.
+type scenario struct {
+	clientID   string
+	qty        int64
+	disconnect bool
+	shared     bool
+}
+
+// stress starts the service pacing values on a short interval while the state sweeper evicts states on a short TTL,
+// then runs a client for each scenario concurrently against it.
+func stress(t *testing.T, scenarios func(i int) scenario) {
+	t.Helper()
+
+	clients := StressClients
+	if testing.Short() {
+		clients /= 10
+	}
+
+	client, svc := serve(t, nil,
+		WithStore(store.NewMemory(500*time.Millisecond)),
+		WithPacing(pacing.Policy{Mode: pacing.Fixed, Interval: 50 * time.Millisecond}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go svc.MaintainStates(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(sc scenario) {
+			defer wg.Done()
+			if err := play(client, sc); err != nil {
+				t.Errorf("client-id %q: %s", sc.clientID, err)
+			}
+		}(scenarios(i))
+	}
+	wg.Wait()
+}
+
+// play runs a scenario against the service until the stream completes.
+// Clients that disconnect resume using their token and must still match the checksum,
+// clients sharing a client-id may be superseded and only need to see contiguous values.
+func play(client v1.ServiceClient, sc scenario) error {
+	var (
+		token    []byte
+		next     = int64(-1)
+		total    = new(big.Int)
+		checksum = new(big.Int)
+	)
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		if sc.clientID != "" {
+			ctx = withClientID(ctx, sc.clientID)
+		}
+
+		stream, err := client.Random(ctx, &v1.Request{Qty: sc.qty, Token: token})
+		if err != nil {
+			cancel()
+
+			return err
+		}
+
+		dropped := false
+		for !dropped {
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				cancel()
+				if !sc.shared && total.Cmp(checksum) != 0 {
+					return fmt.Errorf("checksum mismatch: total %s checksum %s", total, checksum)
+				}
+
+				return nil
+			}
+			if err != nil {
+				cancel()
+				if status.Code(err) == codes.Aborted && sc.shared {
+					return nil
+				}
+
+				return err
+			}
+
+			if response.Token == nil {
+				checksum.SetBytes(response.Checksum)
+
+				continue
+			}
+			if next >= 0 && response.Sequence != next {
+				cancel()
+
+				return fmt.Errorf("expected sequence %d received %d", next, response.Sequence)
+			}
+			next = response.Sequence + 1
+			token = response.Token
+			total.Add(total, new(big.Int).SetBytes(response.Value))
+
+			if sc.disconnect && attempt == 1 {
+				dropped = true
+				cancel()
+			}
+		}
+	}
+}
+
+func TestStressAnonymous(t *testing.T) {
+	t.Parallel()
+	stress(t, func(int) scenario {
+		return scenario{qty: 10}
+	})
+}
+
+func TestStressResume(t *testing.T) {
+	t.Parallel()
+	stress(t, func(i int) scenario {
+		return scenario{clientID: fmt.Sprintf("resume-%d", i), qty: 10, disconnect: true}
+	})
+}
+
+func TestStressSharedClientID(t *testing.T) {
+	t.Parallel()
+	stress(t, func(i int) scenario {
+		return scenario{clientID: fmt.Sprintf("shared-%d", i/2), qty: 10, shared: true}
+	})
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// window bounds the number of values the client has asked to have in flight.
+func window(requested uint32) int64 {
+	switch {
+	case requested == 0:
+		return DefaultWindow
+	case requested > MaxWindow:
+		return MaxWindow
+	default:
+		return int64(requested)
+	}
+}
+
+// receiveAcks forwards acknowledgements from the client until the stream ends,
+// the error ending the stream is delivered once the acknowledgements are exhausted.
+func receiveAcks(ctx context.Context, stream v1.Service_SubscribeServer, acks chan<- *v1.Ack, errs chan<- error) {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			errs <- err
+
+			return
+		}
+
+		ack := msg.GetAck()
+		if ack == nil {
+			errs <- invalid(violation("ack", "only acknowledgements may follow the subscription"))
+
+			return
+		}
+
+		select {
+		case acks <- ack:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Subscribe streams the requested sequence, only committing the client's position as values are acknowledged.
+// At most the window of values may be unacknowledged at any time, on resume any unacknowledged values are sent
+// again and the checksum is sent once every value has been acknowledged.
+func (s *Service) Subscribe(stream v1.Service_SubscribeServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	sub := msg.GetSubscribe()
+	if sub == nil {
+		return invalid(violation("subscribe", "the first message must be a subscription"))
+	}
+
+	req := sub.GetRequest()
+	ctx, sess, release, err := s.open(stream.Context(), "Subscribe", sub.GetGenerator(), req)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	st := sess.state
+	// a token identifies what the client has received, without one the sequence starts afresh.
+	// A retained state resumes from its last acknowledgement when behind the token so unacknowledged values are
+	// sent again, a regenerated state has no acknowledgements so those before the token are taken as acknowledged.
+	if sess.resume != nil {
+		if err := s.resume(sess); err != nil {
+			return err
+		}
+		if committed := st.Committed(); sess.stored && committed < st.Position() {
+			if _, err := st.Seek(committed, io.SeekStart); err != nil {
+				return status.Error(codes.OutOfRange, err.Error())
+			}
+		} else if err := st.Commit(st.Position()); err != nil {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+	}
+
+	acks := make(chan *v1.Ack)
+	errs := make(chan error, 1)
+	go receiveAcks(ctx, stream, acks, errs)
+
+	var (
+		length   = st.Quantity()
+		inflight = window(sub.GetWindow())
+		paced    <-chan time.Time
+	)
+	for {
+		// the checksum follows the last value once every value has been acknowledged
+		if st.Position() == length && st.Committed() == length {
+			return stream.Send(sess.checksum())
+		}
+
+		position := st.Position()
+		if paced == nil && position < length && position-st.Committed() < inflight {
+			res, err := sess.value()
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := s.quotas.Spend(sess.subject); err != nil {
+				return limited(stream.Context(), err)
+			}
+			start := time.Now()
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+			s.observer.ValueSent(sess.method, time.Since(start))
+			st.Next()
+			s.save(sess.clientID, st)
+			paced = time.After(sess.pacer.Delay())
+		}
+
+		select {
+		case ack := <-acks:
+			if ack.GetSequence() >= st.Position() {
+				return status.Errorf(codes.OutOfRange, "acknowledged position %d has not been sent", ack.GetSequence())
+			}
+			if err := st.Commit(ack.GetSequence() + 1); err != nil {
+				return status.Errorf(codes.OutOfRange, "acknowledged position %d: %s", ack.GetSequence(), err)
+			}
+			if ack.GetWindow() > 0 {
+				inflight = window(ack.GetWindow())
+			}
+			s.save(sess.clientID, st)
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return status.Error(codes.Canceled, "client closed the stream before acknowledging every value")
+			}
+
+			return err
+		case <-paced:
+			paced = nil
+		case <-ctx.Done():
+			return interrupted(stream.Context())
+		case <-s.draining:
+			return s.goAway(stream, sess, st.Committed())
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// Quiet is how long a subscription must stay silent for no further response to be expected.
+const Quiet = 100 * time.Millisecond
+
+// subscription reads the responses of a Subscribe stream in the background so their absence can be observed,
+// responses is closed once the stream ends with err.
+type subscription struct {
+	stream    v1.Service_SubscribeClient
+	responses chan *v1.Response
+	err       chan error
+}
+
+// subscribe opens a Subscribe stream for the doubler sequence requested.
+func subscribe(ctx context.Context, t *testing.T, client v1.ServiceClient, req *v1.Request, window uint32) *subscription {
+	t.Helper()
+
+	stream, err := client.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := &v1.Subscription{Generator: "doubler", Request: req, Window: window}
+	if err := stream.Send(&v1.SubscribeRequest{Message: &v1.SubscribeRequest_Subscribe{Subscribe: sub}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &subscription{stream: stream, responses: make(chan *v1.Response, MaxWindow), err: make(chan error, 1)}
+	go func() {
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				s.err <- err
+				close(s.responses)
+
+				return
+			}
+			s.responses <- res
+		}
+	}()
+
+	return s
+}
+
+// next waits for the next response.
+func (s *subscription) next(t *testing.T) *v1.Response {
+	t.Helper()
+
+	select {
+	case res, ok := <-s.responses:
+		if !ok {
+			t.Fatalf("stream ended awaiting a response: %v", <-s.err)
+		}
+
+		return res
+	case <-time.After(time.Second):
+		t.Fatal("no response received")
+	}
+
+	return nil
+}
+
+// values waits for the next n values, failing should any be out of order.
+func (s *subscription) values(t *testing.T, from int64, n int) []*big.Int {
+	t.Helper()
+
+	values := make([]*big.Int, n)
+	for i := range values {
+		res := s.next(t)
+		if res.Token == nil || res.Sequence != from+int64(i) {
+			t.Fatalf("expected the value at %d, got sequence %d token %x", from+int64(i), res.Sequence, res.Token)
+		}
+		values[i] = new(big.Int).SetBytes(res.Value)
+	}
+
+	return values
+}
+
+// quiet fails should a response be received before Quiet elapses.
+func (s *subscription) quiet(t *testing.T) {
+	t.Helper()
+
+	select {
+	case res, ok := <-s.responses:
+		if !ok {
+			t.Fatalf("stream ended: %v", <-s.err)
+		}
+		t.Fatalf("unexpected response at %d", res.Sequence)
+	case <-time.After(Quiet):
+	}
+}
+
+// ack acknowledges every value up to and including sequence.
+func (s *subscription) ack(t *testing.T, sequence int64) {
+	t.Helper()
+
+	if err := s.stream.Send(&v1.SubscribeRequest{Message: &v1.SubscribeRequest_Ack{Ack: &v1.Ack{Sequence: sequence}}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checksum waits for the checksum and the stream to then end.
+func (s *subscription) checksum(t *testing.T) *big.Int {
+	t.Helper()
+
+	res := s.next(t)
+	if res.Token != nil {
+		t.Fatalf("expected the checksum, got the value at %d", res.Sequence)
+	}
+	if err := <-s.err; !errors.Is(err, io.EOF) {
+		t.Fatalf("stream ended with %v", err)
+	}
+
+	return new(big.Int).SetBytes(res.Checksum)
+}
+
+// sum totals the values.
+func sum(values ...*big.Int) *big.Int {
+	total := new(big.Int)
+	for _, v := range values {
+		total.Add(total, v)
+	}
+
+	return total
+}
+
+func TestSubscribeWindow(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+
+	s := subscribe(context.Background(), t, client, &v1.Request{Qty: 6, Seed: 1}, 3)
+	s.values(t, 0, 3)
+	s.quiet(t)
+
+	// each acknowledgement opens the window to as many values again
+	s.ack(t, 0)
+	s.values(t, 3, 1)
+	s.quiet(t)
+	s.ack(t, 2)
+	s.values(t, 4, 2)
+	s.ack(t, 5)
+	if checksum := s.checksum(t); checksum.Int64() != 63 {
+		t.Fatalf("checksum %s want 63", checksum)
+	}
+}
+
+func TestSubscribeChecksumOnceAcknowledged(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+
+	s := subscribe(context.Background(), t, client, &v1.Request{Qty: 3, Seed: 1}, 0)
+	values := s.values(t, 0, 3)
+	s.quiet(t)
+	s.ack(t, 1)
+	s.quiet(t)
+	s.ack(t, 2)
+	if checksum := s.checksum(t); checksum.Cmp(sum(values...)) != 0 {
+		t.Fatalf("checksum %s want %s", checksum, sum(values...))
+	}
+}
+
+func TestSubscribeAckNotSent(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+
+	s := subscribe(context.Background(), t, client, &v1.Request{Qty: 10, Seed: 1}, 2)
+	s.values(t, 0, 2)
+	s.ack(t, 5)
+	if err := <-s.err; errors.Is(err, io.EOF) {
+		t.Fatal("acknowledging a value not sent was accepted")
+	}
+}
+
+func TestSubscribeResendsUnacknowledged(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+	ctx := withClientID(context.Background(), "unacknowledged")
+
+	req := &v1.Request{Qty: 6, Seed: 1}
+	first, cancel := context.WithCancel(ctx)
+	s := subscribe(first, t, client, req, 4)
+	var responses []*v1.Response
+	for i := 0; i < 4; i++ {
+		responses = append(responses, s.next(t))
+	}
+	// the values following the window are only sent once the acknowledgement has been committed
+	s.ack(t, 1)
+	s.values(t, 4, 2)
+	cancel()
+
+	// the client received values it never acknowledged, those are sent again from the committed position
+	req.Token = responses[3].Token
+	s = subscribe(ctx, t, client, req, 0)
+	resumed := s.next(t)
+	if resumed.Sequence != 2 || new(big.Int).SetBytes(resumed.Total).Int64() != 3 {
+		t.Fatalf("resumed at %d with total %x, want 2 with total 3", resumed.Sequence, resumed.Total)
+	}
+	values := s.values(t, 3, 3)
+	s.ack(t, 5)
+	want := sum(append(values, big.NewInt(1), big.NewInt(2), new(big.Int).SetBytes(resumed.Value))...)
+	if checksum := s.checksum(t); checksum.Cmp(want) != 0 {
+		t.Fatalf("checksum %s want %s", checksum, want)
+	}
+}
+
+func TestSubscribeResumeBehindCommitted(t *testing.T) {
+	t.Parallel()
+	client, _ := serve(t, nil)
+	ctx := withClientID(context.Background(), "behind")
+
+	req := &v1.Request{Qty: 4, Seed: 1}
+	s := subscribe(ctx, t, client, req, 0)
+	var responses []*v1.Response
+	for i := 0; i < 4; i++ {
+		responses = append(responses, s.next(t))
+	}
+	s.ack(t, 3)
+	checksum := s.checksum(t)
+
+	// the client only kept the first value, everything after it is sent again before the checksum
+	req.Token = responses[0].Token
+	s = subscribe(ctx, t, client, req, 0)
+	first := s.next(t)
+	if first.Sequence != 1 || new(big.Int).SetBytes(first.Total).Int64() != 1 {
+		t.Fatalf("resumed at %d with total %x, want 1 with total 1", first.Sequence, first.Total)
+	}
+	values := append([]*big.Int{big.NewInt(1), new(big.Int).SetBytes(first.Value)}, s.values(t, 2, 2)...)
+	if got := s.checksum(t); got.Cmp(checksum) != 0 || got.Cmp(sum(values...)) != 0 {
+		t.Fatalf("checksum %s want %s totalling %s", got, checksum, sum(values...))
+	}
+}
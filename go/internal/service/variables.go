@@ -3,23 +3,48 @@ package service
 import (
 	"github.com/rs/zerolog"
 	"os"
-	"time"
 )
 
 // StateTTL represents the number of seconds state should be retained for.
 const (
-	// StateTTL how many seconds should the service maintain state for
+	// StateTTL default number of seconds the service should maintain state for
 	StateTTL = 30
 
 	// MaxSeed upper limit for seeding service
 	MaxSeed = 0xff
 
+	// SecretBits is the size of the seed chosen for sequences the client cannot regenerate.
+	SecretBits = 128
+
 	// MaxQty upper limit for the number of values to be returned
 	MaxQty = 0xffff
+
+	// RetryAfterKey is the trailer carrying how many seconds to wait before retrying a stream which exceeded a quota.
+	RetryAfterKey = "retry-after"
 )
 
 // logger represents a configured instance of zerolog.
 var logger = zerolog.New(os.Stderr).With().Timestamp().Logger().Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
-// interval defines the period to wait between generating new values in sequence.
-var interval = time.Duration(1) * time.Second
+const (
+	// DefaultWindow is the number of values which may be sent without being acknowledged if the client does not specify.
+	DefaultWindow = 16
+
+	// MaxWindow upper limit for the number of values which may be sent without being acknowledged
+	MaxWindow = 1024
+)
+
+// Kinds of identity namespacing the client-id a state is retained against.
+const (
+	// CertNamespace prefixes the subject of a verified client certificate.
+	CertNamespace = "cert"
+
+	// TenantNamespace prefixes the tenant of an authenticated token.
+	TenantNamespace = "tenant"
+
+	// PeerNamespace prefixes the address of an anonymous client, the subject of its quotas.
+	PeerNamespace = "peer"
+
+	// IDNamespace prefixes the client-id supplied in the request metadata.
+	IDNamespace = "id"
+)
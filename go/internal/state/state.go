@@ -1,120 +1,485 @@
 package state
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"exercise/internal/generator"
+	"exercise/internal/integrity"
+	"io"
 	"math/big"
-	"sync/atomic"
+	"sync"
 	"time"
+	"unsafe"
+)
+
+// CheckpointInterval is the number of values between the totals a state retains,
+// bounding how many values must be regenerated to find the total at any position.
+const CheckpointInterval = 4096
+
+var (
+	ErrWhence   = errors.New("invalid whence")
+	ErrRange    = errors.New("position out of range of the sequence")
+	ErrDetached = errors.New("state has no iterator to generate values from")
 )
 
 type Stateful interface {
 	Position() int64
+	Seek(int64, int) (int64, error)
 	Quantity() int64
 	Require() int64
 	Current() *big.Int
 	Last() *big.Int
 	Next() bool
-	Set([]*big.Int)
 	Add(...*big.Int)
 	Total() *big.Int
+	TotalAt(int64) (*big.Int, error)
+	Chain() []byte
 	Accessed() time.Time
-	Sequence() []*big.Int
+}
+
+// Source describes how the values of a state are generated so they can be regenerated on demand.
+type Source struct {
+	// Generator the values are produced by.
+	Generator string
+	// Seed the values are generated from.
+	Seed *big.Int
+	// Params passed to the generator.
+	Params map[string]string
+}
+
+// Matches reports whether both sources generate the same values with the same generator, seed and parameters,
+// a nil seed matches any as the seed of a sequence kept secret is only known to the source retaining it.
+func (src *Source) Matches(other *Source) bool {
+	if src == nil || other == nil || src.Generator != other.Generator || len(src.Params) != len(other.Params) {
+		return false
+	}
+	if src.Seed != nil && other.Seed != nil && src.Seed.Cmp(other.Seed) != 0 {
+		return false
+	}
+
+	for name, value := range src.Params {
+		if v, ok := other.Params[name]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
 }
 
 // State contains the given state of a grpc request containing the last value generated and the number of values
-// seen by the state as well as materialising the number of values generated and the sum of those values.
+// seen by the state as well as the sum and hash chain of those values. Values are generated on demand by the attached iterator
+// rather than held, so a state is the same size however many values are requested.
+// A State is safe for concurrent use.
 type State struct {
+	mu sync.Mutex
 	// quantity of values requested.
 	qty int64
 	// a cursor for tracking the current value when iterating.
 	cursor int64
+	// committed is the position before which all values have been acknowledged by the client.
+	committed int64
+	// source the values are generated from, nil for states tracking values received.
+	source *Source
+	// iter generates the values, it has produced the values before the cursor and the current value if held.
+	iter generator.Iterator
+	// current value at the cursor, nil until generated.
+	current *big.Int
 	// last value to have been processed.
-	sequence []*big.Int
+	last *big.Int
+	// total of the values before the cursor.
+	total *big.Int
+	// chain is the head of the hash chain over the values before the cursor.
+	chain []byte
+	// checkpoints holds the total before every CheckpointInterval'th position the cursor has passed.
+	checkpoints []*big.Int
+	// links holds the head of the chain at each checkpoint.
+	links [][]byte
 	// accessed time the state was last accessed.
 	accessed time.Time
 }
 
 // Position returns the value of the cursor
 func (s *State) Position() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return s.cursor
 }
 
-// Seek sets the cursort to the specified position
-func (s *State) Seek(position int64) {
+// Seek sets the cursor to the position given by offset relative to whence, as per io.Seeker.
+// Seeking to the end of the sequence is permitted, leaving no further values to iterate.
+// The values before the position are regenerated to keep the total, from the closest checkpoint when seeking backwards.
+func (s *State) Seek(offset int64, whence int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var position int64
+
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = s.cursor + offset
+	case io.SeekEnd:
+		position = s.qty + offset
+	default:
+		return s.cursor, ErrWhence
+	}
+
+	if position < 0 || position > s.qty {
+		return s.cursor, ErrRange
+	}
+	if position == s.cursor {
+		s.accessed = time.Now()
+
+		return position, nil
+	}
+	if s.iter == nil {
+		return s.cursor, ErrDetached
+	}
+
+	s.seek(position)
+	s.accessed = time.Now()
+
+	return position, nil
+}
+
+// seek moves the cursor to position, starting from the closest checkpoint before position
+// should it be closer than the cursor, otherwise from the start when seeking backwards.
+func (s *State) seek(position int64) {
+	checkpoint := (position - 1) / CheckpointInterval
+	switch from := checkpoint * CheckpointInterval; {
+	case position > 0 && checkpoint < int64(len(s.checkpoints)) && (position < s.cursor || from > s.cursor):
+		generator.Skip(s.iter, from)
+		s.cursor = from
+		s.current = nil
+		s.last = nil
+		s.total = new(big.Int).Set(s.checkpoints[checkpoint])
+		s.chain = s.links[checkpoint]
+	case position < s.cursor:
+		s.iter.Reset()
+		s.cursor = 0
+		s.current = nil
+		s.last = nil
+		s.total = big.NewInt(0)
+		s.chain = integrity.Genesis()
+	}
+
+	for s.cursor < position {
+		s.step()
+	}
+}
+
+// step moves the cursor past the current value, adding it to the total.
+func (s *State) step() {
+	value := s.current
+	if value == nil {
+		value = s.iter.Next()
+	}
+
+	s.current = nil
+	s.advance(value)
+}
+
+// advance moves the cursor past value, adding it to the total and chain and recording a checkpoint when due.
+func (s *State) advance(value *big.Int) {
+	s.total.Add(s.total, value)
+	s.chain = integrity.Link(s.chain, s.cursor, value)
+	s.last = value
+	s.cursor++
+
+	if s.cursor%CheckpointInterval == 0 && s.cursor/CheckpointInterval == int64(len(s.checkpoints)) {
+		s.checkpoints = append(s.checkpoints, new(big.Int).Set(s.total))
+		s.links = append(s.links, s.chain)
+	}
+}
+
+// Committed returns the position before which all values have been acknowledged.
+func (s *State) Committed() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.committed
+}
+
+// Commit records all values before position as acknowledged, the committed position never moves backwards.
+func (s *State) Commit(position int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if position < 0 || position > s.qty {
+		return ErrRange
+	}
+
 	s.accessed = time.Now()
-	s.cursor = position
+	if position > s.committed {
+		s.committed = position
+	}
+
+	return nil
 }
 
 // Quantity returns the originally requested quantity for this state.
 func (s *State) Quantity() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return s.qty
 }
 
 // Require advises how many values are required to meet requested quantity.
 func (s *State) Require() int64 {
-	return s.qty - int64(len(s.sequence))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.qty - s.cursor
 }
 
-// Current returns the latest value to have been added to state.
+// Current returns the value at the cursor, generating it if need be,
+// nil once the cursor has passed the end of the sequence or no iterator is attached.
 func (s *State) Current() *big.Int {
-	s.accessed = time.Now()
-	return s.sequence[s.cursor]
-}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-// Set the sequence, also resets the cursor.
-func (s *State) Set(seq []*big.Int) {
-	s.sequence = seq
-	s.cursor = 0
 	s.accessed = time.Now()
+	if s.current == nil && s.iter != nil && s.cursor < s.qty {
+		s.current = s.iter.Next()
+	}
+
+	return s.current
 }
 
-// Add a new number to the sequence.
+// Add records values received, moving the cursor past each and adding them to the total.
 func (s *State) Add(values ...*big.Int) {
-	s.sequence = append(s.sequence, values...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, value := range values {
+		s.advance(value)
+	}
 	s.accessed = time.Now()
 }
 
-// Last returns the last item in the sequence, this does not move the cursor.
+// Last returns the last value processed, this does not move the cursor.
 func (s *State) Last() *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.accessed = time.Now()
-	return s.sequence[len(s.sequence)-1]
+	return s.last
 }
 
 // Next moves the cursor forward in the sequence by one and
 // returns true if the cursor remains within range of the sequence
 // returns false if out of range of the sequence.
 func (s *State) Next() bool {
-	atomic.AddInt64(&s.cursor, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.accessed = time.Now()
-	return s.cursor < int64(len(s.sequence))
+	if s.cursor >= s.qty || (s.current == nil && s.iter == nil) {
+		return false
+	}
+
+	s.step()
+
+	return s.cursor < s.qty
 }
 
-// Total generates the sum total of all values in the sequence.
+// Total returns the sum of the values before the cursor.
 func (s *State) Total() *big.Int {
-	total := big.NewInt(0)
-	for _, s := range s.sequence {
-		total.Add(total, s)
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.accessed = time.Now()
-	return total
+	return new(big.Int).Set(s.total)
 }
 
-// Sequence returns the entire sequence currently stored.
-func (s *State) Sequence() []*big.Int {
-	s.accessed = time.Now()
-	return s.sequence
+// TotalAt returns the sum of the values before position without moving the cursor,
+// regenerating the values from the closest checkpoint when position is not the cursor.
+func (s *State) TotalAt(position int64) (*big.Int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if position < 0 || position > s.qty {
+		return nil, ErrRange
+	}
+	if position == s.cursor {
+		return new(big.Int).Set(s.total), nil
+	}
+	if position%CheckpointInterval == 0 && position/CheckpointInterval < int64(len(s.checkpoints)) {
+		return new(big.Int).Set(s.checkpoints[position/CheckpointInterval]), nil
+	}
+	if s.iter == nil {
+		return nil, ErrDetached
+	}
+
+	cursor, current, last, total, chain := s.cursor, s.current, s.last, new(big.Int).Set(s.total), s.chain
+	s.seek(position)
+	at := new(big.Int).Set(s.total)
+
+	// restore the cursor, the iterator having produced the current value if held
+	s.cursor, s.current, s.last, s.total, s.chain = cursor, current, last, total, chain
+	if current != nil {
+		generator.Skip(s.iter, cursor+1)
+	} else {
+		generator.Skip(s.iter, cursor)
+	}
+
+	return at, nil
+}
+
+// Chain returns the head of the hash chain over the values before the cursor.
+func (s *State) Chain() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.chain
+}
+
+// Source returns the description of how the values are generated, nil for states tracking values received.
+func (s *State) Source() *Source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.source
+}
+
+// Attached reports whether an iterator is attached to generate the values.
+func (s *State) Attached() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.iter != nil
+}
+
+// Attach sets the iterator generating the values, moving it to the cursor.
+// States restored by UnmarshalBinary must have an iterator attached before values can be generated.
+func (s *State) Attach(iter generator.Iterator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	generator.Skip(iter, s.cursor)
+	s.iter = iter
+	s.current = nil
 }
 
 // Accessed returns the timme the state was last accessed.
 func (s *State) Accessed() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return s.accessed
 }
 
-// NewState instantiate a new state object
-func NewState(qty int64, seq []*big.Int) *State {
+// Size estimates the bytes of memory held by the state, as values are generated on demand it grows only with the checkpoints.
+func (s *State) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := int64(unsafe.Sizeof(*s)) + int64(len(s.chain))
+	for _, n := range []*big.Int{s.current, s.last, s.total} {
+		if n != nil {
+			size += int64(len(n.Bits())) * int64(unsafe.Sizeof(big.Word(0)))
+		}
+	}
+	for i, checkpoint := range s.checkpoints {
+		size += int64(len(checkpoint.Bits()))*int64(unsafe.Sizeof(big.Word(0))) + int64(len(s.links[i]))
+	}
+	if s.source != nil {
+		for name, value := range s.source.Params {
+			size += int64(len(name) + len(value))
+		}
+	}
+
+	return size
+}
+
+// snapshot is the serialisable form of a State, the iterator is not retained and must be attached once restored.
+type snapshot struct {
+	Qty         int64
+	Cursor      int64
+	Committed   int64
+	Source      *Source
+	Last        *big.Int
+	Total       *big.Int
+	Chain       []byte
+	Checkpoints []*big.Int
+	Links       [][]byte
+	Accessed    time.Time
+}
+
+// MarshalBinary encodes the state so that it may be persisted, implementing encoding.BinaryMarshaler.
+func (s *State) MarshalBinary() ([]byte, error) {
+	s.mu.Lock()
+	snap := snapshot{
+		Qty:         s.qty,
+		Cursor:      s.cursor,
+		Committed:   s.committed,
+		Source:      s.source,
+		Last:        s.last,
+		Total:       s.total,
+		Chain:       s.chain,
+		Checkpoints: s.checkpoints,
+		Links:       s.links,
+		Accessed:    s.accessed,
+	}
+	s.mu.Unlock()
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(snap)
+
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary restores a state encoded by MarshalBinary, implementing encoding.BinaryUnmarshaler.
+func (s *State) UnmarshalBinary(data []byte) error {
+	var snap snapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snap); err != nil {
+		return err
+	}
+	if snap.Total == nil {
+		snap.Total = big.NewInt(0)
+	}
+	if len(snap.Chain) == 0 {
+		snap.Chain = integrity.Genesis()
+	}
+	if len(snap.Checkpoints) == 0 || len(snap.Links) != len(snap.Checkpoints) {
+		snap.Checkpoints = []*big.Int{big.NewInt(0)}
+		snap.Links = [][]byte{integrity.Genesis()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.qty = snap.Qty
+	s.cursor = snap.Cursor
+	s.committed = snap.Committed
+	s.source = snap.Source
+	s.iter = nil
+	s.current = nil
+	s.last = snap.Last
+	s.total = snap.Total
+	s.chain = snap.Chain
+	s.checkpoints = snap.Checkpoints
+	s.links = snap.Links
+	s.accessed = snap.Accessed
+
+	return nil
+}
+
+// NewState instantiate a new state object, values are generated from the source once an iterator is attached
+// whereas a state without a source tracks values as they are added.
+func NewState(qty int64, source *Source) *State {
 	return &State{
-		qty:      qty,
-		cursor:   int64(0),
-		sequence: seq,
-		accessed: time.Now(),
+		qty:         qty,
+		cursor:      int64(0),
+		source:      source,
+		total:       big.NewInt(0),
+		chain:       integrity.Genesis(),
+		checkpoints: []*big.Int{big.NewInt(0)},
+		links:       [][]byte{integrity.Genesis()},
+		accessed:    time.Now(),
 	}
 }
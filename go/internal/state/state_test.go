@@ -0,0 +1,248 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"exercise/internal/generator"
+	"exercise/internal/random"
+	"fmt"
+	"io"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// counter produces the sequence 1, 2, 3... seeking directly to any position.
+type counter struct {
+	next int64
+}
+
+func (c *counter) Next() *big.Int {
+	c.next++
+
+	return big.NewInt(c.next)
+}
+
+func (c *counter) Reset()                { c.next = 0 }
+func (c *counter) SeekTo(position int64) { c.next = position }
+
+// counting returns a state attached to the sequence 1, 2, 3... whose total before position p is p(p+1)/2.
+func counting(qty int64) *State {
+	st := NewState(qty, &Source{Generator: "counter"})
+	st.Attach(&counter{})
+
+	return st
+}
+
+// triangle returns the total of the counting sequence before position.
+func triangle(position int64) *big.Int {
+	return big.NewInt(position * (position + 1) / 2)
+}
+
+func TestSeekCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	qty := int64(3*CheckpointInterval + 5)
+	st := counting(qty)
+	if _, err := st.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain at each position as found seeking forwards from the start
+	chains := map[int64][]byte{}
+	positions := []int64{0, 1, CheckpointInterval - 1, CheckpointInterval, CheckpointInterval + 1, 2*CheckpointInterval + 7, 3 * CheckpointInterval, qty - 1, qty}
+	for _, position := range positions {
+		fresh := counting(qty)
+		if _, err := fresh.Seek(position, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		chains[position] = fresh.Chain()
+	}
+
+	// seeking backwards and forwards resumes from the closest checkpoint, arriving at the same total and chain
+	for _, i := range []int{8, 0, 5, 1, 7, 3, 2, 6, 4} {
+		position := positions[i]
+		if _, err := st.Seek(position, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if got := st.Total(); got.Cmp(triangle(position)) != 0 {
+			t.Fatalf("seeking to %d total %s want %s", position, got, triangle(position))
+		}
+		if !bytes.Equal(st.Chain(), chains[position]) {
+			t.Fatalf("seeking to %d the chain differs from seeking forwards", position)
+		}
+		if position < qty {
+			if got := st.Current(); got.Int64() != position+1 {
+				t.Fatalf("seeking to %d current %s want %d", position, got, position+1)
+			}
+		}
+	}
+
+	if _, err := st.Seek(qty+1, io.SeekStart); !errors.Is(err, ErrRange) {
+		t.Fatalf("seeking beyond the sequence expected %s, got %v", ErrRange, err)
+	}
+}
+
+func TestTotalAt(t *testing.T) {
+	t.Parallel()
+
+	qty := int64(2*CheckpointInterval + 100)
+	st := counting(qty)
+	cursor := int64(CheckpointInterval + 50)
+	if _, err := st.Seek(cursor, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	// hold the current value so restoring the cursor must also account for it
+	st.Current()
+
+	for _, position := range []int64{0, 1, cursor, cursor - 1, cursor + 1, CheckpointInterval, 2 * CheckpointInterval, qty} {
+		got, err := st.TotalAt(position)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(triangle(position)) != 0 {
+			t.Fatalf("total at %d is %s want %s", position, got, triangle(position))
+		}
+		if st.Position() != cursor || st.Current().Int64() != cursor+1 || st.Total().Cmp(triangle(cursor)) != 0 {
+			t.Fatalf("total at %d moved the cursor to %d", position, st.Position())
+		}
+	}
+
+	// the values after the cursor continue the sequence
+	for position := cursor; position < cursor+3; position++ {
+		if got := st.Current(); got.Int64() != position+1 {
+			t.Fatalf("after totalling, value at %d is %s want %d", position, got, position+1)
+		}
+		st.Next()
+	}
+
+	if _, err := st.TotalAt(qty + 1); !errors.Is(err, ErrRange) {
+		t.Fatalf("total beyond the sequence expected %s, got %v", ErrRange, err)
+	}
+}
+
+func TestTotalAtDetached(t *testing.T) {
+	t.Parallel()
+
+	st := NewState(10, nil)
+	st.Add(big.NewInt(1), big.NewInt(2), big.NewInt(3))
+
+	if got, err := st.TotalAt(3); err != nil || got.Int64() != 6 {
+		t.Fatalf("total at the cursor %v, %v", got, err)
+	}
+	if _, err := st.TotalAt(1); !errors.Is(err, ErrDetached) {
+		t.Fatalf("total before the cursor without an iterator expected %s, got %v", ErrDetached, err)
+	}
+}
+
+// BenchmarkQuantities are the lengths of the sequences benchmarked, re-summing is quadratic in the quantity.
+var BenchmarkQuantities = []int64{1 << 10, 1 << 14}
+
+// benchmarkIterator builds a random iterator drawing from the default distribution.
+func benchmarkIterator(b *testing.B) generator.Iterator {
+	b.Helper()
+
+	g := random.Generator{}
+	params, err := generator.Validate(g, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	iter, err := g.Iterator(big.NewInt(1), params)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return iter
+}
+
+// values materialises qty values so tallying benchmarks do not measure their generation.
+func values(iter generator.Iterator, qty int64) []*big.Int {
+	iter.Reset()
+	seq := make([]*big.Int, qty)
+	for i := range seq {
+		seq[i] = iter.Next()
+	}
+
+	return seq
+}
+
+// BenchmarkResum tallies each value received by re-summing everything received so far,
+// as the state did before keeping a running total.
+func BenchmarkResum(b *testing.B) {
+	for _, qty := range BenchmarkQuantities {
+		seq := values(benchmarkIterator(b), qty)
+		b.Run(fmt.Sprintf("qty=%d", qty), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				var received []*big.Int
+				for _, value := range seq {
+					received = append(received, value)
+					total := new(big.Int)
+					for _, v := range received {
+						total.Add(total, v)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkIncremental tallies each value received into the running total.
+func BenchmarkIncremental(b *testing.B) {
+	for _, qty := range BenchmarkQuantities {
+		seq := values(benchmarkIterator(b), qty)
+		b.Run(fmt.Sprintf("qty=%d", qty), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				st := NewState(qty, nil)
+				for _, value := range seq {
+					st.Add(value)
+					st.Total()
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGenerate generates and totals the whole sequence.
+func BenchmarkGenerate(b *testing.B) {
+	for _, qty := range BenchmarkQuantities {
+		iter := benchmarkIterator(b)
+		b.Run(fmt.Sprintf("qty=%d", qty), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				st := NewState(qty, &Source{Generator: random.Name})
+				iter.Reset()
+				st.Attach(iter)
+				for ok := st.Position() < qty; ok; ok = st.Next() {
+					st.Current()
+				}
+				st.Total()
+			}
+		})
+	}
+}
+
+// BenchmarkTotalAt finds the total before an arbitrary position of a completed sequence.
+func BenchmarkTotalAt(b *testing.B) {
+	for _, qty := range BenchmarkQuantities {
+		iter := benchmarkIterator(b)
+		positions := rand.New(rand.NewSource(1))
+		b.Run(fmt.Sprintf("qty=%d", qty), func(b *testing.B) {
+			st := NewState(qty, &Source{Generator: random.Name})
+			iter.Reset()
+			st.Attach(iter)
+			if _, err := st.Seek(0, io.SeekEnd); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if _, err := st.TotalAt(positions.Int63n(qty)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"exercise/internal/state"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucket is the name of the bbolt bucket states are persisted to.
+var bucket = []byte("states")
+
+// File is a Store backed by a bbolt database on local disk so that states survive a restart.
+// States are served from memory, changes are written to disk on Flush and when the store is closed.
+type File struct {
+	*Memory
+	db    *bolt.DB
+	mu    sync.Mutex
+	dirty map[string]struct{}
+}
+
+// Save records the state for the client, it is persisted on the next Flush.
+func (f *File) Save(clientID string, st *state.State) error {
+	if err := f.Memory.Save(clientID, st); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.dirty[clientID] = struct{}{}
+	f.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes any state held for the client from memory and disk.
+func (f *File) Delete(clientID string) error {
+	if err := f.Memory.Delete(clientID); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	delete(f.dirty, clientID)
+	f.mu.Unlock()
+
+	return f.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(clientID))
+	})
+}
+
+// Expire evicts all states not accessed within the TTL from memory and disk.
+func (f *File) Expire() ([]string, error) {
+	evicted, err := f.Memory.Expire()
+	if err != nil || len(evicted) == 0 {
+		return evicted, err
+	}
+
+	f.mu.Lock()
+	for _, id := range evicted {
+		delete(f.dirty, id)
+	}
+	f.mu.Unlock()
+
+	return evicted, f.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, id := range evicted {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Flush writes all states saved since the last flush to disk in a single transaction.
+func (f *File) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.dirty) == 0 {
+		return nil
+	}
+
+	err := f.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for id := range f.dirty {
+			st, ok := f.Memory.Load(id)
+			if !ok {
+				continue
+			}
+			data, err := st.MarshalBinary()
+			if err != nil {
+				return fmt.Errorf("unable to encode state for %s: %w", id, err)
+			}
+			if err := b.Put([]byte(id), data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err == nil {
+		f.dirty = map[string]struct{}{}
+	}
+
+	return err
+}
+
+// Close flushes outstanding changes and closes the underlying database.
+func (f *File) Close() error {
+	if err := f.Flush(); err != nil {
+		_ = f.db.Close()
+
+		return err
+	}
+
+	return f.db.Close()
+}
+
+// load populates memory with the states persisted on disk.
+func (f *File) load() error {
+	return f.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			st := &state.State{}
+			if err := st.UnmarshalBinary(v); err != nil {
+				return fmt.Errorf("unable to decode state for %s: %w", k, err)
+			}
+
+			return f.Memory.Save(string(k), st)
+		})
+	})
+}
+
+// NewFile opens, creating if required, the bbolt database at path and loads any states it holds.
+// States already older than ttl are evicted on the first call to Expire.
+func NewFile(path string, ttl time.Duration) (*File, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	f := &File{
+		Memory: NewMemory(ttl),
+		db:     db,
+		dirty:  map[string]struct{}{},
+	}
+	if err := f.load(); err != nil {
+		_ = db.Close()
+
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"exercise/internal/state"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// shards is the number of independently locked partitions states are spread across,
+// reducing contention between streams for different clients.
+const shards = 32
+
+// shard is a partition of the states held by a Memory store.
+type shard struct {
+	mu     sync.RWMutex
+	states map[string]*state.State
+}
+
+// Memory is a Store holding states in memory, they are lost when the process exits.
+// A Memory store is safe for concurrent use.
+type Memory struct {
+	ttl    time.Duration
+	shards [shards]*shard
+}
+
+// shard returns the partition responsible for the client.
+func (m *Memory) shard(clientID string) *shard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(clientID))
+
+	return m.shards[h.Sum32()%shards]
+}
+
+// Load returns the state held for the client, reporting whether one was found.
+func (m *Memory) Load(clientID string) (*state.State, bool) {
+	sh := m.shard(clientID)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	st, ok := sh.states[clientID]
+
+	return st, ok
+}
+
+// Save records the state for the client.
+func (m *Memory) Save(clientID string, st *state.State) error {
+	sh := m.shard(clientID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.states[clientID] = st
+
+	return nil
+}
+
+// Delete removes any state held for the client.
+func (m *Memory) Delete(clientID string) error {
+	sh := m.shard(clientID)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	delete(sh.states, clientID)
+
+	return nil
+}
+
+// Expire evicts all states not accessed within the TTL, returning the ids of the clients evicted.
+func (m *Memory) Expire() ([]string, error) {
+	var evicted []string
+	now := time.Now()
+
+	for _, sh := range m.shards {
+		sh.mu.Lock()
+		for id, st := range sh.states {
+			if now.Sub(st.Accessed()) > m.ttl {
+				delete(sh.states, id)
+				evicted = append(evicted, id)
+			}
+		}
+		sh.mu.Unlock()
+	}
+
+	return evicted, nil
+}
+
+// Range calls fn for each state held, stopping early if fn returns false.
+// fn must not call back into the store.
+func (m *Memory) Range(fn func(clientID string, st *state.State) bool) {
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+		for id, st := range sh.states {
+			if !fn(id, st) {
+				sh.mu.RUnlock()
+
+				return
+			}
+		}
+		sh.mu.RUnlock()
+	}
+}
+
+// Len returns the number of states held.
+func (m *Memory) Len() int {
+	n := 0
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+		n += len(sh.states)
+		sh.mu.RUnlock()
+	}
+
+	return n
+}
+
+// Flush is a no-op as there is nowhere to persist to.
+func (m *Memory) Flush() error {
+	return nil
+}
+
+// Close is a no-op as no resources are held.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// NewMemory instantiates an empty in memory store evicting states after ttl.
+func NewMemory(ttl time.Duration) *Memory {
+	m := &Memory{ttl: ttl}
+	for i := range m.shards {
+		m.shards[i] = &shard{states: map[string]*state.State{}}
+	}
+
+	return m
+}
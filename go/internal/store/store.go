@@ -0,0 +1,26 @@
+package store
+
+import (
+	"exercise/internal/state"
+)
+
+// Store retains the state of client sequences between requests so streams may be resumed.
+// Implementations are responsible for evicting states that have not been accessed within their TTL.
+type Store interface {
+	// Load returns the state held for the client, reporting whether one was found.
+	Load(clientID string) (*state.State, bool)
+	// Save records the state for the client.
+	Save(clientID string, st *state.State) error
+	// Delete removes any state held for the client.
+	Delete(clientID string) error
+	// Expire evicts all states not accessed within the TTL, returning the ids of the clients evicted.
+	Expire() ([]string, error)
+	// Range calls fn for each state held, stopping early if fn returns false.
+	Range(fn func(clientID string, st *state.State) bool)
+	// Len returns the number of states held.
+	Len() int
+	// Flush persists any outstanding changes.
+	Flush() error
+	// Close flushes and releases any resources held by the store.
+	Close() error
+}
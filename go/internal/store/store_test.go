@@ -0,0 +1,249 @@
+package store
+
+import (
+	"exercise/internal/state"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tracking returns a state tracking values received, having received the values given.
+func tracking(qty int64, values ...int64) *state.State {
+	st := state.NewState(qty, nil)
+	for _, v := range values {
+		st.Add(big.NewInt(v))
+	}
+
+	return st
+}
+
+// reopen opens the file store at path, closed once the test ends, failing unless it holds n states.
+func reopen(t *testing.T, path string, ttl time.Duration, n int) *File {
+	t.Helper()
+
+	f, err := NewFile(path, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	if f.Len() != n {
+		t.Fatalf("reopened with %d states want %d", f.Len(), n)
+	}
+
+	return f
+}
+
+// snapshot copies the database at path as a process dying now would leave it, returning the path of the copy.
+func snapshot(t *testing.T, path string) string {
+	t.Helper()
+
+	src, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	copied := filepath.Join(t.TempDir(), "snapshot.db")
+	dst, err := os.Create(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatal(err)
+	}
+
+	return copied
+}
+
+func TestMemorySharded(t *testing.T) {
+	t.Parallel()
+	m := NewMemory(time.Hour)
+
+	const clients = 256
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("client-%d", i)
+			_ = m.Save(id, tracking(10, int64(i)))
+			if st, ok := m.Load(id); !ok || st.Last().Int64() != int64(i) {
+				t.Errorf("%s loaded %v", id, st)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	used := 0
+	for _, sh := range m.shards {
+		if len(sh.states) > 0 {
+			used++
+		}
+	}
+	if used < shards/2 {
+		t.Fatalf("%d clients spread across %d of %d shards", clients, used, shards)
+	}
+
+	seen := map[string]bool{}
+	m.Range(func(id string, _ *state.State) bool {
+		seen[id] = true
+
+		return true
+	})
+	if len(seen) != clients || m.Len() != clients {
+		t.Fatalf("ranged over %d of %d states", len(seen), m.Len())
+	}
+	visited := 0
+	m.Range(func(string, *state.State) bool {
+		visited++
+
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Fatalf("range continued to %d states once stopped", visited)
+	}
+
+	_ = m.Delete("client-0")
+	if _, ok := m.Load("client-0"); ok || m.Len() != clients-1 {
+		t.Fatalf("deleted state still held among %d", m.Len())
+	}
+}
+
+func TestMemoryExpire(t *testing.T) {
+	t.Parallel()
+	m := NewMemory(50 * time.Millisecond)
+
+	stale, fresh := tracking(5, 1), tracking(5, 1)
+	_ = m.Save("stale", stale)
+	_ = m.Save("fresh", fresh)
+	time.Sleep(60 * time.Millisecond)
+	fresh.Add(big.NewInt(2))
+
+	evicted, err := m.Expire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 1 || evicted[0] != "stale" {
+		t.Fatalf("evicted %v", evicted)
+	}
+	if _, ok := m.Load("fresh"); !ok {
+		t.Fatal("state accessed within the ttl evicted")
+	}
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "states.db")
+
+	f, err := NewFile(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := tracking(10, 3, 6, 12)
+	if err := st.Commit(2); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Save("a", st)
+	_ = f.Save("b", tracking(4))
+	_ = f.Save("deleted", tracking(4))
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Delete("deleted")
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f = reopen(t, path, time.Hour, 2)
+	restored, ok := f.Load("a")
+	if !ok {
+		t.Fatal("state not restored")
+	}
+	// reading the last value accesses the state, so when it was last accessed is checked first
+	if !restored.Accessed().Equal(st.Accessed()) {
+		t.Fatalf("restored as accessed at %s want %s", restored.Accessed(), st.Accessed())
+	}
+	if restored.Position() != 3 || restored.Committed() != 2 || restored.Quantity() != 10 ||
+		restored.Total().Int64() != 21 || restored.Last().Int64() != 12 {
+		t.Fatalf("restored at %d committed %d total %s last %s", restored.Position(), restored.Committed(), restored.Total(), restored.Last())
+	}
+	if string(restored.Chain()) != string(st.Chain()) {
+		t.Fatal("hash chain not restored")
+	}
+}
+
+func TestFileFlushesDirtyStates(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "states.db")
+
+	f, err := NewFile(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// states are only written to disk once flushed, so a process dying before then loses them
+	st := tracking(10, 1)
+	_ = f.Save("a", st)
+	reopen(t, snapshot(t, path), time.Hour, 0)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopen(t, snapshot(t, path), time.Hour, 1)
+
+	// a state saved again is rewritten by the next flush
+	st.Add(big.NewInt(2))
+	_ = f.Save("a", st)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if restored, _ := reopen(t, snapshot(t, path), time.Hour, 1).Load("a"); restored.Position() != 2 {
+		t.Fatalf("flushed state restored at %d", restored.Position())
+	}
+	if len(f.dirty) != 0 {
+		t.Fatalf("%d states dirty once flushed", len(f.dirty))
+	}
+}
+
+func TestFileExpire(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "states.db")
+	ttl := 50 * time.Millisecond
+
+	f, err := NewFile(path, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, fresh := tracking(5, 1), tracking(5, 1)
+	_ = f.Save("stale", stale)
+	_ = f.Save("fresh", fresh)
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * ttl)
+	fresh.Add(big.NewInt(2))
+	_ = f.Save("fresh", fresh)
+
+	// the evicted state is removed from disk as well as memory
+	if evicted, err := f.Expire(); err != nil || len(evicted) != 1 || evicted[0] != "stale" {
+		t.Fatalf("evicted %v: %v", evicted, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f = reopen(t, path, ttl, 1)
+	if _, ok := f.Load("fresh"); !ok {
+		t.Fatal("state accessed within the ttl not restored")
+	}
+
+	// states which went stale while the store was closed are evicted by the first expiry
+	time.Sleep(2 * ttl)
+	if evicted, err := f.Expire(); err != nil || len(evicted) != 1 {
+		t.Fatalf("evicted %v once reopened: %v", evicted, err)
+	}
+}
@@ -0,0 +1,61 @@
+package token
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// version identifies the encoding of the token so the format can evolve without breaking resuming clients.
+const version = byte(2)
+
+var (
+	ErrInvalid = errors.New("invalid resume token")
+	ErrVersion = errors.New("unsupported resume token version")
+)
+
+// Token identifies where a stream should resume from.
+type Token struct {
+	// Position of the next value to be sent.
+	Position int64
+	// Seed the sequence was generated from, allowing it to be regenerated when chosen by the server.
+	Seed int64
+}
+
+// Encode builds the opaque form of the token sent to clients.
+func (t Token) Encode() []byte {
+	buf := make([]byte, 1+2*binary.MaxVarintLen64)
+	buf[0] = version
+	n := 1
+	n += binary.PutVarint(buf[n:], t.Position)
+	n += binary.PutVarint(buf[n:], t.Seed)
+
+	return buf[:n]
+}
+
+// Decode parses an opaque token generated by Encode.
+func Decode(data []byte) (Token, error) {
+	var t Token
+	if len(data) < 3 {
+		return t, ErrInvalid
+	}
+
+	if data[0] != version {
+		return t, ErrVersion
+	}
+
+	n := 1
+	for _, field := range []*int64{&t.Position, &t.Seed} {
+		value, read := binary.Varint(data[n:])
+		if read <= 0 {
+			return t, ErrInvalid
+		}
+		*field = value
+		n += read
+	}
+
+	if n != len(data) || t.Position < 0 {
+		return t, ErrInvalid
+	}
+
+	return t, nil
+}
@@ -0,0 +1,70 @@
+package token
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, tok := range []Token{
+		{},
+		{Position: 1, Seed: 1},
+		{Position: 63, Seed: -64},
+		{Position: 64, Seed: 255},
+		{Position: math.MaxInt64, Seed: math.MinInt64},
+	} {
+		encoded := tok.Encode()
+		if encoded[0] != version {
+			t.Fatalf("%+v: encoded as version %d", tok, encoded[0])
+		}
+		decoded, err := Decode(encoded)
+		if err != nil || decoded != tok {
+			t.Fatalf("%+v: decoded as %+v: %v", tok, decoded, err)
+		}
+	}
+
+	// small positions and seeds take a byte each
+	if n := len(Token{Position: 10, Seed: 5}.Encode()); n != 3 {
+		t.Fatalf("encoded in %d bytes", n)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	t.Parallel()
+	valid := Token{Position: 300, Seed: 7}.Encode()
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "empty", err: ErrInvalid},
+		{name: "too short", data: []byte{version, 0}, err: ErrInvalid},
+		{name: "earlier version", data: []byte{1, 2, 2}, err: ErrVersion},
+		{name: "later version", data: append([]byte{version + 1}, valid[1:]...), err: ErrVersion},
+		{name: "truncated", data: valid[:len(valid)-1], err: ErrInvalid},
+		{name: "trailing bytes", data: append(append([]byte{}, valid...), 0), err: ErrInvalid},
+		{name: "unterminated varint", data: []byte{version, 0x80, 0x80}, err: ErrInvalid},
+		{name: "overflowing varint", data: append([]byte{version}, append(overflow(), 0)...), err: ErrInvalid},
+		{name: "negative position", data: Token{Position: -1}.Encode(), err: ErrInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := Decode(tt.data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %s, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// overflow returns a varint too long for 64 bits.
+func overflow() []byte {
+	data := make([]byte, 11)
+	for i := range data[:10] {
+		data[i] = 0xff
+	}
+	data[10] = 0x01
+
+	return data
+}
@@ -21,6 +21,12 @@ type ServiceClient interface {
 	// Start the stream of numbers
 	Doubler(ctx context.Context, in *Request, opts ...grpc.CallOption) (Service_DoublerClient, error)
 	Random(ctx context.Context, in *Request, opts ...grpc.CallOption) (Service_RandomClient, error)
+	// Stream a sequence acknowledging values as they are processed, unacknowledged values are resent on resume
+	Subscribe(ctx context.Context, opts ...grpc.CallOption) (Service_SubscribeClient, error)
+	// Stream the sequence of any registered generator
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (Service_GenerateClient, error)
+	// List the registered generators and the parameters they accept
+	ListGenerators(ctx context.Context, in *ListGeneratorsRequest, opts ...grpc.CallOption) (*ListGeneratorsResponse, error)
 }
 
 type serviceClient struct {
@@ -95,6 +101,78 @@ func (x *serviceRandomClient) Recv() (*Response, error) {
 	return m, nil
 }
 
+func (c *serviceClient) Subscribe(ctx context.Context, opts ...grpc.CallOption) (Service_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[2], "/ably.v1.Service/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceSubscribeClient{stream}
+	return x, nil
+}
+
+type Service_SubscribeClient interface {
+	Send(*SubscribeRequest) error
+	Recv() (*Response, error)
+	grpc.ClientStream
+}
+
+type serviceSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceSubscribeClient) Send(m *SubscribeRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *serviceSubscribeClient) Recv() (*Response, error) {
+	m := new(Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (Service_GenerateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Service_ServiceDesc.Streams[3], "/ably.v1.Service/Generate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serviceGenerateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Service_GenerateClient interface {
+	Recv() (*Response, error)
+	grpc.ClientStream
+}
+
+type serviceGenerateClient struct {
+	grpc.ClientStream
+}
+
+func (x *serviceGenerateClient) Recv() (*Response, error) {
+	m := new(Response)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *serviceClient) ListGenerators(ctx context.Context, in *ListGeneratorsRequest, opts ...grpc.CallOption) (*ListGeneratorsResponse, error) {
+	out := new(ListGeneratorsResponse)
+	err := c.cc.Invoke(ctx, "/ably.v1.Service/ListGenerators", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ServiceServer is the server API for Service service.
 // All implementations must embed UnimplementedServiceServer
 // for forward compatibility
@@ -102,6 +180,12 @@ type ServiceServer interface {
 	// Start the stream of numbers
 	Doubler(*Request, Service_DoublerServer) error
 	Random(*Request, Service_RandomServer) error
+	// Stream a sequence acknowledging values as they are processed, unacknowledged values are resent on resume
+	Subscribe(Service_SubscribeServer) error
+	// Stream the sequence of any registered generator
+	Generate(*GenerateRequest, Service_GenerateServer) error
+	// List the registered generators and the parameters they accept
+	ListGenerators(context.Context, *ListGeneratorsRequest) (*ListGeneratorsResponse, error)
 	mustEmbedUnimplementedServiceServer()
 }
 
@@ -115,6 +199,15 @@ func (UnimplementedServiceServer) Doubler(*Request, Service_DoublerServer) error
 func (UnimplementedServiceServer) Random(*Request, Service_RandomServer) error {
 	return status.Errorf(codes.Unimplemented, "method Random not implemented")
 }
+func (UnimplementedServiceServer) Subscribe(Service_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedServiceServer) Generate(*GenerateRequest, Service_GenerateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedServiceServer) ListGenerators(context.Context, *ListGeneratorsRequest) (*ListGeneratorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGenerators not implemented")
+}
 func (UnimplementedServiceServer) mustEmbedUnimplementedServiceServer() {}
 
 // UnsafeServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -170,13 +263,83 @@ func (x *serviceRandomServer) Send(m *Response) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Service_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ServiceServer).Subscribe(&serviceSubscribeServer{stream})
+}
+
+type Service_SubscribeServer interface {
+	Send(*Response) error
+	Recv() (*SubscribeRequest, error)
+	grpc.ServerStream
+}
+
+type serviceSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceSubscribeServer) Send(m *Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *serviceSubscribeServer) Recv() (*SubscribeRequest, error) {
+	m := new(SubscribeRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Service_Generate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GenerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServiceServer).Generate(m, &serviceGenerateServer{stream})
+}
+
+type Service_GenerateServer interface {
+	Send(*Response) error
+	grpc.ServerStream
+}
+
+type serviceGenerateServer struct {
+	grpc.ServerStream
+}
+
+func (x *serviceGenerateServer) Send(m *Response) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Service_ListGenerators_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGeneratorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServiceServer).ListGenerators(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ably.v1.Service/ListGenerators",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServiceServer).ListGenerators(ctx, req.(*ListGeneratorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Service_ServiceDesc is the grpc.ServiceDesc for Service service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Service_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "ably.v1.Service",
 	HandlerType: (*ServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListGenerators",
+			Handler:    _Service_ListGenerators_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Doubler",
@@ -188,6 +351,17 @@ var Service_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Service_Random_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Service_Subscribe_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Generate",
+			Handler:       _Service_Generate_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "server.proto",
 }
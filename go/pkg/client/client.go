@@ -0,0 +1,196 @@
+// Package client streams sequences from the ably exercise server, resuming each stream across lost connections
+// and server restarts and verifying the values received. Configure a Client with options, then range over the
+// events of each Stream:
+//
+//	c, err := client.New("localhost:9090", client.WithReconnectTimeout(time.Minute))
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	stream, err := c.Stream(ctx, client.Request{Generator: "doubler", Qty: 10, Seed: 1})
+//	if err != nil {
+//		return err
+//	}
+//	for event := range stream.Events() {
+//		...
+//	}
+//	return stream.Err()
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+var (
+	ErrSequenceGap = errors.New("values missing from stream")
+	ErrGoingAway   = errors.New("server is going away")
+	ErrDiverged    = errors.New("stream diverged from the server")
+	// ErrInvalidRequest the server rejected the request, retrying it unchanged cannot succeed.
+	ErrInvalidRequest = errors.New("request rejected by the server")
+	// ErrLimitExceeded the request exceeds a limit of the server.
+	ErrLimitExceeded = errors.New("server limit exceeded")
+	// ErrUnauthenticated the server did not accept the credentials supplied, if any.
+	ErrUnauthenticated = errors.New("not authenticated by the server")
+	// ErrTransport the transport requested is unknown or cannot support the options requested.
+	ErrTransport = errors.New("unsupported transport")
+	// ErrUnsupported the transport cannot carry the call.
+	ErrUnsupported = errors.New("not supported by the transport")
+	// ErrFraming the framing requested over a WebSocket is unknown.
+	ErrFraming = errors.New("unsupported framing")
+	// ErrToken the bearer token cannot be read or more than one source of credentials was supplied.
+	ErrToken = errors.New("invalid bearer token")
+	// ErrReconnect a lost stream could not reconnect to the server before the reconnect timeout.
+	ErrReconnect = errors.New("unable to reconnect")
+	// ErrLast a last value was supplied without a state file recording a token to resume from.
+	ErrLast = errors.New("the last value is only checked when resuming")
+)
+
+// Client streams sequences from a server, it is safe to open streams concurrently.
+type Client struct {
+	opts      options
+	transport transport
+	clientID  string
+}
+
+// New configures a client of the server at target, a host and port. Over grpc the connection is established lazily,
+// over a WebSocket a connection is opened for each stream.
+func New(target string, opts ...Option) (*Client, error) {
+	o := options{
+		transport:        GRPC,
+		framing:          Binary,
+		reconnectTimeout: DefaultReconnectTimeout,
+		logger:           zerolog.Nop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &Client{opts: o}
+	if !o.stateless {
+		c.clientID = o.clientID
+		if c.clientID == "" {
+			c.clientID = uuid.New().String()
+		}
+	}
+
+	var err error
+	switch o.transport {
+	case GRPC:
+		c.transport, err = dialGRPC(target, &o)
+	case WebSocket:
+		c.transport, err = dialWebSocket(target, &o)
+	default:
+		err = fmt.Errorf("%w: %s", ErrTransport, o.transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ClientID returns the client-id the server retains the state of streams against, empty when stateless.
+func (c *Client) ClientID() string {
+	return c.clientID
+}
+
+// Close closes the connection to the server, any open streams are lost.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// Health queries the standard grpc health service for the serving status of service,
+// an empty service name reports the overall status of the server.
+func (c *Client) Health(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	health, err := c.transport.health()
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	return resp.GetStatus(), nil
+}
+
+// Generators asks the server which generators it can stream and the parameters each accepts.
+func (c *Client) Generators(ctx context.Context) ([]*v1.GeneratorInfo, error) {
+	resp, err := c.transport.service().ListGenerators(ctx, &v1.ListGeneratorsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetGenerators(), nil
+}
+
+// retryAfter reports how long the server asked the client to wait before retrying a stream which exceeded a quota.
+func retryAfter(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
+
+// rejection converts a status the server rejected the request with into ErrInvalidRequest, ErrLimitExceeded or ErrUnauthenticated,
+// describing each violation it details. Any other error is transient and nil is returned.
+func rejection(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+
+	var kind error
+	switch st.Code() {
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition, codes.Unimplemented:
+		kind = ErrInvalidRequest
+	case codes.ResourceExhausted:
+		kind = ErrLimitExceeded
+	case codes.Unauthenticated, codes.PermissionDenied:
+		kind = ErrUnauthenticated
+	default:
+		return nil
+	}
+
+	var reasons []string
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				reasons = append(reasons, v.GetField()+" "+v.GetDescription())
+			}
+		case *errdetails.QuotaFailure:
+			for _, v := range d.GetViolations() {
+				reasons = append(reasons, v.GetSubject()+" "+v.GetDescription())
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, st.Message())
+	}
+
+	return fmt.Errorf("%w (%s): %s", kind, st.Code(), strings.Join(reasons, ", "))
+}
@@ -0,0 +1,360 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/pacing"
+	"exercise/internal/service"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// listen starts the service on an in-memory listener with the interceptors, values are sent without delay.
+// Everything is stopped once the test ends.
+func listen(t *testing.T, interceptors ...grpc.StreamServerInterceptor) *bufconn.Listener {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	svc := service.NewService(service.WithPacing(pacing.Policy{Mode: pacing.Burst}))
+	srv := grpc.NewServer(grpc.ChainStreamInterceptor(append(interceptors, svc.StreamServerInterceptor())...))
+	v1.RegisterServiceServer(srv, svc)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() {
+		srv.Stop()
+		_ = svc.Close()
+	})
+
+	return lis
+}
+
+// connect creates a client of the service listening on lis, closed once the test ends.
+func connect(t *testing.T, lis *bufconn.Listener, opts ...Option) *Client {
+	t.Helper()
+
+	dialer := func(context.Context, string) (net.Conn, error) { return lis.Dial() }
+	c, err := New("bufconn", append([]Option{WithDialOptions(grpc.WithContextDialer(dialer))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+// drain receives the events of the stream until it finishes, returning them all.
+func drain(s *Stream) []Event {
+	var events []Event
+	for e := range s.Events() {
+		events = append(events, e)
+	}
+
+	return events
+}
+
+// done returns the final event of the stream, failing unless every value was received and verified.
+func done(t *testing.T, events []Event) Event {
+	t.Helper()
+
+	if len(events) == 0 {
+		t.Fatal("no events received")
+	}
+	last := events[len(events)-1]
+	if last.Type != Done || !last.Verified || last.Err != nil {
+		t.Fatalf("stream finished with %s verified %t: %v", last.Type, last.Verified, last.Err)
+	}
+
+	return last
+}
+
+// values returns the events of the type.
+func values(events []Event, kind EventType) []Event {
+	var matched []Event
+	for _, e := range events {
+		if e.Type == kind {
+			matched = append(matched, e)
+		}
+	}
+
+	return matched
+}
+
+// failing fails the first streams opened for each client-id with the error returned by fail, given the number of
+// streams opened so far, once the values have been sent. A nil error leaves the stream to complete.
+func failing(sent int, fail func(opened int) error) grpc.StreamServerInterceptor {
+	var mu sync.Mutex
+	opened := map[string]int{}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		id := strings.Join(md.Get("client-id"), ",")
+		mu.Lock()
+		opened[id]++
+		err := fail(opened[id])
+		mu.Unlock()
+		if err == nil {
+			return handler(srv, ss)
+		}
+		if sent == 0 {
+			return err
+		}
+
+		return handler(srv, &interrupted{ServerStream: ss, remaining: sent, err: err})
+	}
+}
+
+// interrupted fails the stream with err once the remaining values have been sent.
+type interrupted struct {
+	grpc.ServerStream
+	remaining int
+	err       error
+}
+
+func (s *interrupted) SendMsg(m interface{}) error {
+	if s.remaining == 0 {
+		return s.err
+	}
+	s.remaining--
+
+	return s.ServerStream.SendMsg(m)
+}
+
+func TestOptions(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var seen []metadata.MD
+	record := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		mu.Lock()
+		seen = append(seen, md)
+		mu.Unlock()
+
+		return handler(srv, ss)
+	}
+	lis := listen(t, record)
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		clientID      string
+		authorization string
+	}{
+		{name: "client-id", opts: []Option{WithClientID("chosen")}, clientID: "chosen"},
+		{name: "stateless", opts: []Option{WithStateless(), WithClientID("ignored")}},
+		{name: "token", opts: []Option{WithClientID("token"), WithToken("secret")}, clientID: "token", authorization: "Bearer secret"},
+	}
+	for _, tt := range tests {
+		c := connect(t, lis, tt.opts...)
+		if c.ClientID() != tt.clientID {
+			t.Fatalf("%s: client-id %q want %q", tt.name, c.ClientID(), tt.clientID)
+		}
+		s, err := c.Stream(context.Background(), Request{Generator: "doubler", Qty: 1, Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(t, drain(s))
+
+		mu.Lock()
+		md := seen[len(seen)-1]
+		mu.Unlock()
+		if id := strings.Join(md.Get("client-id"), ","); id != tt.clientID {
+			t.Errorf("%s: sent client-id %q want %q", tt.name, id, tt.clientID)
+		}
+		if authorization := strings.Join(md.Get("authorization"), ","); authorization != tt.authorization {
+			t.Errorf("%s: sent authorization %q want %q", tt.name, authorization, tt.authorization)
+		}
+	}
+
+	// a client-id is chosen for each client unless stateless
+	if a, b := connect(t, lis), connect(t, lis); a.ClientID() == "" || a.ClientID() == b.ClientID() {
+		t.Fatalf("clients chose client-ids %q and %q", a.ClientID(), b.ClientID())
+	}
+
+	invalid := []struct {
+		name string
+		opts []Option
+		err  error
+	}{
+		{name: "transport", opts: []Option{WithTransport("carrier-pigeon")}, err: ErrTransport},
+		{name: "token and file", opts: []Option{WithToken("secret"), WithTokenFile("token")}, err: ErrToken},
+	}
+	for _, tt := range invalid {
+		if _, err := New("bufconn", tt.opts...); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %s, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	t.Parallel()
+	c := connect(t, listen(t))
+
+	for _, req := range []Request{
+		{Generator: "doubler", Qty: 4, Seed: 1},
+		{Generator: "doubler", Qty: 4, Seed: 1, Integrity: v1.Integrity_INTEGRITY_CHAIN},
+		{Generator: "arithmetic", Qty: 4, Seed: 1, Params: map[string]string{"step": "3"}},
+		{Generator: "doubler", Qty: 4, Seed: 1, Subscribe: true, Window: 2},
+	} {
+		events := drain(mustStream(t, c, req))
+		last := done(t, events)
+
+		received := values(events, Value)
+		want := []int64{1, 2, 4, 8}
+		if req.Generator == "arithmetic" {
+			want = []int64{1, 4, 7, 10}
+		}
+		total := int64(0)
+		for i, e := range received {
+			total += want[i]
+			if e.Sequence != int64(i) || e.Value.Int64() != want[i] || e.Total.Int64() != total {
+				t.Fatalf("%+v: value %d received as %d at %d totalling %s", req, i, e.Value, e.Sequence, e.Total)
+			}
+			if chained := req.Integrity == v1.Integrity_INTEGRITY_CHAIN; chained != (e.Chain != nil) {
+				t.Fatalf("%+v: value %d carries chain %x", req, i, e.Chain)
+			}
+		}
+		checksums := values(events, Checksum)
+		if len(received) != 4 || len(checksums) != 1 || checksums[0].Checksum.Int64() != total {
+			t.Fatalf("%+v: received %d values and %d checksums", req, len(received), len(checksums))
+		}
+		if last.Sequence != 4 || last.Total.Int64() != total {
+			t.Fatalf("%+v: finished at %d totalling %s", req, last.Sequence, last.Total)
+		}
+	}
+}
+
+// mustStream starts the stream, failing the test should it be refused.
+func mustStream(t *testing.T, c *Client, req Request) *Stream {
+	t.Helper()
+
+	s, err := c.Stream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestRetryAfterQuota(t *testing.T) {
+	t.Parallel()
+
+	delay := 200 * time.Millisecond
+	exhausted, err := status.New(codes.ResourceExhausted, "too many streams").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := listen(t, failing(0, func(opened int) error {
+		if opened == 1 {
+			return exhausted.Err()
+		}
+
+		return nil
+	}))
+
+	started := time.Now()
+	events := drain(mustStream(t, connect(t, lis), Request{Generator: "doubler", Qty: 3, Seed: 1}))
+	done(t, events)
+	if elapsed := time.Since(started); elapsed < delay {
+		t.Fatalf("retried after %s, asked to wait %s", elapsed, delay)
+	}
+	reconnecting := values(events, Reconnecting)
+	if len(reconnecting) != 1 || reconnecting[0].RetryAfter != delay || status.Code(reconnecting[0].Err) != codes.ResourceExhausted {
+		t.Fatalf("reconnected %d times: %+v", len(reconnecting), reconnecting)
+	}
+
+	// without saying when to retry, exceeding a limit fails the stream
+	lis = listen(t, failing(0, func(int) error { return status.Error(codes.ResourceExhausted, "too many values") }))
+	last := drain(mustStream(t, connect(t, lis), Request{Generator: "doubler", Qty: 3, Seed: 1}))
+	if e := last[len(last)-1]; e.Type != Done || !errors.Is(e.Err, ErrLimitExceeded) || len(values(last, Reconnecting)) != 0 {
+		t.Fatalf("expected %s without retrying, got %s: %v", ErrLimitExceeded, e.Type, e.Err)
+	}
+}
+
+func TestResumeFromToken(t *testing.T) {
+	t.Parallel()
+
+	// the first stream of each client is lost after three values
+	lis := listen(t, failing(3, func(opened int) error {
+		if opened == 1 {
+			return status.Error(codes.Unavailable, "connection lost")
+		}
+
+		return nil
+	}))
+
+	for _, req := range []Request{
+		{Generator: "doubler", Qty: 6, Seed: 1},
+		{Generator: "random", Qty: 6},
+		{Generator: "doubler", Qty: 6, Seed: 1, Subscribe: true},
+	} {
+		events := drain(mustStream(t, connect(t, lis), req))
+		if last := done(t, events); last.Sequence != 6 {
+			t.Fatalf("%+v: finished at %d", req, last.Sequence)
+		}
+
+		// the stream resumes after the last value received, which is neither repeated nor skipped
+		reconnecting, resumed := values(events, Reconnecting), values(events, Resumed)
+		if len(reconnecting) != 1 || len(resumed) != 1 || resumed[0].Sequence != 3 || status.Code(reconnecting[0].Err) != codes.Unavailable {
+			t.Fatalf("%+v: reconnected %d times and resumed %d times", req, len(reconnecting), len(resumed))
+		}
+		for i, e := range values(events, Value) {
+			if e.Sequence != int64(i) {
+				t.Fatalf("%+v: value %d received at %d", req, i, e.Sequence)
+			}
+		}
+	}
+
+	// a stateless client cannot resume a generator whose values depend on the state retained
+	events := drain(mustStream(t, connect(t, lis, WithStateless()), Request{Generator: "random", Qty: 6}))
+	if last := events[len(events)-1]; last.Type != Done || last.Err == nil {
+		t.Fatalf("stateless random stream resumed: %s verified %t", last.Type, last.Verified)
+	}
+}
+
+// corrupting alters the chain head of the value sent at position.
+type corrupting struct {
+	grpc.ServerStream
+	position int64
+}
+
+func (s *corrupting) SendMsg(m interface{}) error {
+	if res, ok := m.(*v1.Response); ok && len(res.Chain) > 0 && res.Token != nil && res.Sequence == s.position {
+		res.Chain = append([]byte{}, res.Chain...)
+		res.Chain[0] ^= 0xff
+	}
+
+	return s.ServerStream.SendMsg(m)
+}
+
+func TestChainDiverged(t *testing.T) {
+	t.Parallel()
+	lis := listen(t, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &corrupting{ServerStream: ss, position: 2})
+	})
+
+	// the values before the corrupted head are verified, the stream failing at the first that differs
+	events := drain(mustStream(t, connect(t, lis), Request{Generator: "doubler", Qty: 5, Seed: 1, Integrity: v1.Integrity_INTEGRITY_CHAIN}))
+	last := events[len(events)-1]
+	if last.Type != Done || !errors.Is(last.Err, ErrDiverged) || !strings.Contains(last.Err.Error(), "position 2") {
+		t.Fatalf("expected %s at position 2, got %s: %v", ErrDiverged, last.Type, last.Err)
+	}
+	if received := values(events, Value); len(received) != 2 {
+		t.Fatalf("received %d values before diverging", len(received))
+	}
+
+	// summed integrity carries no chain to verify
+	done(t, drain(mustStream(t, connect(t, lis), Request{Generator: "doubler", Qty: 5, Seed: 1})))
+}
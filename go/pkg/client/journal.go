@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrJournal is returned when the state file cannot be resumed from.
+var ErrJournal = errors.New("unable to resume from state file")
+
+// header describes the request a journal records the values of, it is the first line of the journal.
+type header struct {
+	ClientID  string            `json:"client_id"`
+	Generator string            `json:"generator"`
+	Qty       int64             `json:"qty"`
+	Seed      int64             `json:"seed"`
+	Params    map[string]string `json:"params,omitempty"`
+	Integrity int32             `json:"integrity"`
+}
+
+// entry records a value received, each following line of the journal.
+type entry struct {
+	Sequence int64  `json:"sequence"`
+	Value    []byte `json:"value"`
+	Token    []byte `json:"token"`
+}
+
+// journal durably records the values received on a stream so that a client which dies mid-stream can resume
+// where it left off. It is a file of JSON lines, the header describing the request followed by an entry per value,
+// each synced to disk before the value is acknowledged.
+type journal struct {
+	path string
+	file *os.File
+}
+
+// append records a value received, returning once it is synced to disk.
+func (j *journal) append(e entry) error {
+	return j.write(e)
+}
+
+// begin records the header describing the request as the first line of a new journal.
+func (j *journal) begin(h header) error {
+	return j.write(h)
+}
+
+// write appends v as a line of the journal, syncing it to disk.
+func (j *journal) write(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
+
+// remove deletes the journal once its sequence is complete.
+func (j *journal) remove() error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(j.path)
+}
+
+// close releases the journal file, leaving it to be resumed from.
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// replay reads the header and entries recorded in the file. Each line is written with its newline in a single write,
+// a line without one was torn as the process died while writing it and is discarded, a torn header leaving a new
+// journal. Reading stops at the first entry which cannot be read. The offset of the end of the last line read is returned.
+func replay(f *os.File) (*header, []entry, int64, error) {
+	r := bufio.NewReader(f)
+
+	var (
+		h       *header
+		entries []entry
+		offset  int64
+	)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		if h == nil {
+			h = &header{}
+			if err := json.Unmarshal(line, h); err != nil {
+				return nil, nil, 0, fmt.Errorf("%w: unreadable header: %s", ErrJournal, err)
+			}
+		} else {
+			var e entry
+			if err := json.Unmarshal(line, &e); err != nil {
+				break
+			}
+			if e.Sequence != int64(len(entries)) {
+				return nil, nil, 0, fmt.Errorf("%w: expected sequence %d found %d", ErrJournal, len(entries), e.Sequence)
+			}
+			entries = append(entries, e)
+		}
+		offset += int64(len(line))
+	}
+
+	return h, entries, offset, nil
+}
+
+// openJournal opens the journal at path, replaying the header and entries recorded should it already exist.
+// Any partially written line is discarded so the journal can be appended to, a nil header reports a new journal.
+func openJournal(path string) (*journal, *header, []entry, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	h, entries, offset, err := replay(f)
+	if err == nil {
+		err = f.Truncate(offset)
+	}
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+
+		return nil, nil, nil, err
+	}
+
+	return &journal{path: path, file: f}, h, entries, nil
+}
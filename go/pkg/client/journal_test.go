@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// written creates a journal at a new path holding the header and n entries, returning its path.
+func written(t *testing.T, h header, n int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	j, existing, _, err := openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing != nil {
+		t.Fatalf("new journal replayed header %+v", existing)
+	}
+	if err := j.begin(h); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := j.append(entry{Sequence: int64(i), Value: []byte{byte(i + 1)}, Token: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// tear appends a partially written line to the file, as left by a process dying while writing it.
+func tear(t *testing.T, path, line string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(line); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reopen opens the journal at path, expecting it to replay n entries.
+func reopen(t *testing.T, path string, n int) (*journal, *header) {
+	t.Helper()
+
+	j, h, entries, err := openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = j.close() })
+	if len(entries) != n {
+		t.Fatalf("replayed %d entries want %d", len(entries), n)
+	}
+	for i, e := range entries {
+		if e.Sequence != int64(i) || e.Value[0] != byte(i+1) {
+			t.Fatalf("entry %d replayed as %+v", i, e)
+		}
+	}
+
+	return j, h
+}
+
+func TestJournalReopened(t *testing.T) {
+	t.Parallel()
+
+	want := header{ClientID: "id", Generator: "doubler", Qty: 10, Seed: 2}
+	path := written(t, want, 3)
+
+	j, h := reopen(t, path, 3)
+	if h == nil || h.ClientID != want.ClientID || h.Generator != want.Generator || h.Qty != want.Qty || h.Seed != want.Seed {
+		t.Fatalf("replayed header %+v want %+v", h, want)
+	}
+	if err := j.append(entry{Sequence: 3, Value: []byte{4}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = j.close()
+
+	reopen(t, path, 4)
+}
+
+func TestJournalTornEntry(t *testing.T) {
+	t.Parallel()
+
+	path := written(t, header{Generator: "doubler", Qty: 10}, 2)
+	tear(t, path, `{"sequence":2,"val`)
+
+	// the torn entry is discarded so the entry appended in its place can be replayed
+	j, _ := reopen(t, path, 2)
+	if err := j.append(entry{Sequence: 2, Value: []byte{3}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = j.close()
+
+	reopen(t, path, 3)
+}
+
+func TestJournalTornHeader(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	tear(t, path, `{"client_id":"id","gener`)
+
+	// a torn header leaves a new journal to begin afresh
+	j, h := reopen(t, path, 0)
+	if h != nil {
+		t.Fatalf("torn header replayed as %+v", h)
+	}
+	if err := j.begin(header{Generator: "doubler", Qty: 5}); err != nil {
+		t.Fatal(err)
+	}
+	_ = j.close()
+
+	if _, h := reopen(t, path, 0); h == nil || h.Generator != "doubler" {
+		t.Fatalf("header replayed as %+v", h)
+	}
+}
+
+func TestJournalUnresumable(t *testing.T) {
+	t.Parallel()
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+	tear(t, corrupt, "not a header\n")
+
+	gap := written(t, header{Generator: "doubler", Qty: 10}, 1)
+	tear(t, gap, `{"sequence":5}`+"\n")
+
+	for _, path := range []string{corrupt, gap} {
+		if _, _, _, err := openJournal(path); !errors.Is(err, ErrJournal) {
+			t.Errorf("%s: expected %s, got %v", filepath.Base(path), ErrJournal, err)
+		}
+	}
+}
+
+func TestStreamResumesFromStateFile(t *testing.T) {
+	t.Parallel()
+	lis := listen(t)
+
+	for _, generator := range []string{"doubler", "random"} {
+		path := filepath.Join(t.TempDir(), generator+".json")
+		req := Request{Generator: generator, Qty: 8, Seed: 1, StateFile: path}
+
+		s, err := connect(t, lis).Stream(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if e := <-s.Events(); e.Type != Value {
+				t.Fatalf("%s: expected a value, got %s", generator, e.Type)
+			}
+		}
+		s.Close()
+
+		// a client restarted with the state file resumes the request it records with the same client-id
+		req = Request{Generator: generator, StateFile: path}
+		s, err = connect(t, lis).Stream(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := drain(s)
+		if last := done(t, events); last.Sequence != 8 {
+			t.Fatalf("%s: finished at %d", generator, last.Sequence)
+		}
+		if first := events[0]; first.Type != Value || first.Sequence < 3 {
+			t.Fatalf("%s: restarted stream began with %s at %d", generator, first.Type, first.Sequence)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s: state file kept once complete: %v", generator, err)
+		}
+	}
+}
+
+func TestLastRequiresStateFile(t *testing.T) {
+	t.Parallel()
+	c := connect(t, listen(t))
+
+	if _, err := c.Stream(context.Background(), Request{Generator: "doubler", Qty: 5, Last: 4}); !errors.Is(err, ErrLast) {
+		t.Fatalf("expected %s, got %v", ErrLast, err)
+	}
+
+	// the last value journalled is replaced by the one supplied, which the server checks
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := c.Stream(context.Background(), Request{Generator: "doubler", Qty: 5, Seed: 1, StateFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-s.Events()
+	s.Close()
+
+	s, err = c.Stream(context.Background(), Request{Generator: "doubler", StateFile: path, Last: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := drain(s)
+	if e := last[len(last)-1]; e.Type != Done || !errors.Is(e.Err, ErrInvalidRequest) {
+		t.Fatalf("expected the server to reject the last value, got %s: %v", e.Type, e.Err)
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// options configure a Client, set by each Option.
+type options struct {
+	transport        Transport
+	framing          Framing
+	tls              *tls.Config
+	token            string
+	tokenFile        string
+	credentials      credentials.PerRPCCredentials
+	clientID         string
+	stateless        bool
+	reconnectTimeout time.Duration
+	dialOptions      []grpc.DialOption
+	logger           zerolog.Logger
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+// WithTransport sets how the server is reached, grpc by default.
+func WithTransport(transport Transport) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithFraming sets how messages are framed over a WebSocket, binary by default.
+func WithFraming(framing Framing) Option {
+	return func(o *options) {
+		o.framing = framing
+	}
+}
+
+// WithTLS connects over TLS verifying the server, and presenting any client certificate, as configured.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// WithToken sends the bearer token, a JWT or api key, with every call to authenticate the client.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTokenFile sends the bearer token held in the file with every call, read afresh for each so it may be rotated.
+func WithTokenFile(path string) Option {
+	return func(o *options) {
+		o.tokenFile = path
+	}
+}
+
+// WithCredentials sets the credentials sent with every call, in place of a bearer token.
+func WithCredentials(creds credentials.PerRPCCredentials) Option {
+	return func(o *options) {
+		o.credentials = creds
+	}
+}
+
+// WithClientID sets the client-id the server retains the state of each stream against, random by default.
+func WithClientID(id string) Option {
+	return func(o *options) {
+		o.clientID = id
+	}
+}
+
+// WithStateless sends no client-id so the server retains no state, the streams of a stateful generator
+// cannot then be resumed.
+func WithStateless() Option {
+	return func(o *options) {
+		o.stateless = true
+	}
+}
+
+// WithReconnectTimeout sets how long a lost stream keeps trying to reconnect for, DefaultReconnectTimeout by default.
+func WithReconnectTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.reconnectTimeout = timeout
+	}
+}
+
+// WithDialOptions adds options used to dial the server over grpc.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// WithLogger sets the logger the client reports its progress to, nothing is logged by default.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
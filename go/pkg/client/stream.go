@@ -0,0 +1,540 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"exercise/internal/doubler"
+	"exercise/internal/random"
+	"exercise/internal/state"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	grpcRetry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// Request describes the sequence to stream.
+type Request struct {
+	// Generator of the sequence, such as doubler or random.
+	Generator string
+	// Qty of values in the sequence.
+	Qty int64
+	// Seed of the sequence, zero allows the server to choose.
+	Seed int64
+	// Params passed to the generator, omitted parameters take the generator's defaults.
+	Params map[string]string
+	// Last value seen by the client, checked by the server against the value before the position resumed from.
+	// It requires a StateFile recording the values received to resume from.
+	Last int64
+	// Interval requested between values, bounded by the server, zero accepts the server default.
+	Interval time.Duration
+	// Integrity of the values, with the chain each value is verified as it is received.
+	Integrity v1.Integrity
+	// Subscribe streams bidirectionally acknowledging each value, unacknowledged values are resent on resume.
+	Subscribe bool
+	// Window of values which may be unacknowledged when subscribed, zero accepts the server default.
+	Window uint32
+	// StateFile the values received are journalled to, a stream started with the file left by one which stopped
+	// mid-stream resumes the request it records in place of this one.
+	StateFile string
+}
+
+// EventType distinguishes the events of a stream.
+type EventType int
+
+const (
+	// Value a value of the sequence was received.
+	Value EventType = iota
+	// Checksum the server sent the checksum of the sequence.
+	Checksum
+	// Reconnecting the stream was lost and is being resumed.
+	Reconnecting
+	// Resumed the stream was resumed, the next value received follows the last.
+	Resumed
+	// Done the stream finished, it is the last event.
+	Done
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Value:
+		return "value"
+	case Checksum:
+		return "checksum"
+	case Reconnecting:
+		return "reconnecting"
+	case Resumed:
+		return "resumed"
+	case Done:
+		return "done"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event reports the progress of a stream.
+type Event struct {
+	Type EventType
+	// Sequence position of the value received, the stream resumed from, or the values received once done.
+	Sequence int64
+	// Value received.
+	Value *big.Int
+	// Total of the values received so far.
+	Total *big.Int
+	// Checksum sent by the server.
+	Checksum *big.Int
+	// Chain head of the values received, when verified by chain.
+	Chain []byte
+	// Verified once done, the total matched the checksum sent by the server.
+	Verified bool
+	// Err why the stream was lost when reconnecting, or why it failed once done.
+	Err error
+	// RetryAfter when reconnecting, how long the server asked the client to wait before resuming.
+	RetryAfter time.Duration
+}
+
+// Stream delivers the events of a sequence being streamed from the server.
+type Stream struct {
+	client *Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	// clientID sent with each request, empty when stateless.
+	clientID string
+	// generator, seed, params and integrity of the sequence requested.
+	req Request
+	// state tallying the values received.
+	state state.Stateful
+	// token of the last value received, used to resume the stream.
+	token []byte
+	// last value received, checked by the server against the value before the position resumed from.
+	// Zero when unknown or it exceeds an int64.
+	last int64
+	// journal the values received are durably recorded in, nil unless a state file is supplied.
+	journal *journal
+	events  chan Event
+	err     error
+	// closed once the caller abandons the stream, finished once the stream has finished.
+	closed   chan struct{}
+	finished chan struct{}
+	once     sync.Once
+}
+
+// fatal marks an error which resuming the stream cannot recover from.
+type fatal struct {
+	error
+}
+
+func (f fatal) Unwrap() error {
+	return f.error
+}
+
+// Stream starts streaming the requested sequence, which continues until every value has been received, it fails
+// or ctx is done. The events must be received until the channel is closed, unless the stream is closed.
+func (c *Client) Stream(ctx context.Context, req Request) (*Stream, error) {
+	if req.Subscribe && c.opts.transport != GRPC {
+		return nil, fmt.Errorf("%w: subscribe requires %s", ErrTransport, GRPC)
+	}
+
+	s := &Stream{
+		client:   c,
+		clientID: c.clientID,
+		req:      req,
+		state:    state.NewState(req.Qty, nil),
+		events:   make(chan Event),
+		closed:   make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	if req.StateFile != "" {
+		if err := s.resume(req.StateFile); err != nil {
+			return nil, err
+		}
+	}
+	// the value the client claims to have seen last replaces the one journalled for the server to check
+	if req.Last != 0 {
+		if s.token == nil {
+			if s.journal != nil {
+				_ = s.journal.close()
+			}
+
+			return nil, fmt.Errorf("%w: no values received to resume from", ErrLast)
+		}
+		s.last = req.Last
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	go s.run()
+
+	return s, nil
+}
+
+// resume reloads the journal at path, should it record a request the stream resumes it with the same client-id
+// from the values recorded, otherwise the request is recorded in a new journal.
+func (s *Stream) resume(path string) error {
+	j, h, entries, err := openJournal(path)
+	if err != nil {
+		return err
+	}
+
+	if h == nil {
+		err = j.begin(header{
+			ClientID:  s.clientID,
+			Generator: s.req.Generator,
+			Qty:       s.req.Qty,
+			Seed:      s.req.Seed,
+			Params:    s.req.Params,
+			Integrity: int32(s.req.Integrity),
+		})
+		if err != nil {
+			_ = j.close()
+
+			return err
+		}
+		s.journal = j
+
+		return nil
+	}
+
+	if h.Generator != s.req.Generator {
+		_ = j.close()
+
+		return fmt.Errorf("%w: it records the %s generator not %s", ErrJournal, h.Generator, s.req.Generator)
+	}
+
+	// the request recorded takes precedence so the values received so far remain part of the same sequence
+	s.clientID = h.ClientID
+	s.req.Qty = h.Qty
+	s.req.Seed = h.Seed
+	s.req.Params = h.Params
+	s.req.Integrity = v1.Integrity(h.Integrity)
+	s.state = state.NewState(h.Qty, nil)
+	for _, e := range entries {
+		s.record(new(big.Int).SetBytes(e.Value), e.Token)
+	}
+	s.journal = j
+
+	s.client.opts.logger.Info().
+		Str("client-id", h.ClientID).
+		Str("generator", h.Generator).
+		Int("received", len(entries)).
+		Msgf("Resuming from %s", path)
+
+	return nil
+}
+
+// Events returns the events of the stream, the channel is closed once the stream has finished.
+// The last event is Done unless the stream was closed.
+func (s *Stream) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns why the stream failed once the events are closed, nil should every value have been received.
+func (s *Stream) Err() error {
+	<-s.finished
+
+	return s.err
+}
+
+// Close abandons the stream, returning once it has finished. A journalled stream may be resumed from its state file.
+func (s *Stream) Close() {
+	s.once.Do(func() { close(s.closed) })
+	s.cancel()
+	<-s.finished
+}
+
+// emit delivers the event, false should the stream have been closed.
+func (s *Stream) emit(e Event) bool {
+	select {
+	case s.events <- e:
+		return true
+	case <-s.closed:
+		return false
+	}
+}
+
+// run streams the sequence resuming it each time it is lost until it finishes.
+func (s *Stream) run() {
+	defer close(s.finished)
+	defer close(s.events)
+	defer s.cancel()
+
+	verified, err := s.loop()
+	s.err = err
+	s.finish(verified && err == nil)
+
+	event := Event{Type: Done, Sequence: s.state.Position(), Total: s.state.Total(), Verified: verified, Err: err}
+	if s.req.Integrity == v1.Integrity_INTEGRITY_CHAIN {
+		event.Chain = s.state.Chain()
+	}
+	s.emit(event)
+}
+
+// loop receives the stream, reconnecting and resuming it each time it is lost unless the server rejected the
+// request in which case retrying cannot succeed and the stream finishes with the rejection. A stream which exceeded
+// a quota of the server is retried once the server says the quota will have recovered.
+func (s *Stream) loop() (bool, error) {
+	reconnected := false
+	for {
+		verified, err := s.receive(reconnected)
+		if err == nil {
+			return verified, nil
+		}
+
+		var f fatal
+		if errors.As(err, &f) {
+			return false, f.error
+		}
+		if s.ctx.Err() != nil {
+			return false, s.ctx.Err()
+		}
+
+		wait, quota := retryAfter(err)
+		if !quota {
+			if rejected := rejection(err); rejected != nil {
+				return false, rejected
+			}
+		}
+
+		if !s.emit(Event{Type: Reconnecting, Sequence: s.state.Position(), Err: err, RetryAfter: wait}) {
+			return false, s.ctx.Err()
+		}
+		if quota {
+			select {
+			case <-s.ctx.Done():
+				return false, s.ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		if err := s.reconnect(); err != nil {
+			return false, err
+		}
+		reconnected = true
+	}
+}
+
+// reconnect waits for the server to become reachable, giving up after the reconnect timeout.
+func (s *Stream) reconnect() error {
+	timeout := s.client.opts.reconnectTimeout
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+
+	if err := s.client.transport.reconnect(ctx); err != nil {
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+
+		return fmt.Errorf("%w: timed out after %s", ErrReconnect, timeout)
+	}
+
+	return nil
+}
+
+// finish removes the journal once the sequence has been received in full, otherwise it is kept to resume from.
+func (s *Stream) finish(success bool) {
+	if s.journal == nil {
+		return
+	}
+
+	var err error
+	if success {
+		err = s.journal.remove()
+	} else {
+		err = s.journal.close()
+	}
+	if err != nil {
+		s.client.opts.logger.Error().Err(err).Msg("Unable to close state file")
+	}
+}
+
+// record adds a value received to the state, resuming from its token.
+func (s *Stream) record(value *big.Int, token []byte) {
+	s.state.Add(value)
+	s.token = token
+	s.last = 0
+	if value.IsInt64() {
+		s.last = value.Int64()
+	}
+}
+
+// diverged reports the position at which the values received first differed from those sent.
+func diverged(position int64, reason string) error {
+	return fatal{fmt.Errorf("%w at position %d: %s", ErrDiverged, position, reason)}
+}
+
+// clientStream is a stream of responses from any of the rpcs streaming a sequence.
+type clientStream interface {
+	Recv() (*v1.Response, error)
+	grpc.ClientStream
+}
+
+// subscribe opens the bidirectional stream subscribing to the generator's sequence.
+func (s *Stream) subscribe(ctx context.Context, service v1.ServiceClient, req *v1.Request) (clientStream, error) {
+	stream, err := service.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&v1.SubscribeRequest{Message: &v1.SubscribeRequest_Subscribe{Subscribe: &v1.Subscription{
+		Generator: s.req.Generator,
+		Request:   req,
+		Window:    s.req.Window,
+	}}})
+	if err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+// acknowledge tells the server every value up to and including sequence has been processed,
+// streams not opened with subscribe have nothing to acknowledge.
+func acknowledge(stream clientStream, sequence int64) error {
+	if subscription, ok := stream.(v1.Service_SubscribeClient); ok {
+		err := subscription.Send(&v1.SubscribeRequest{Message: &v1.SubscribeRequest_Ack{Ack: &v1.Ack{Sequence: sequence}}})
+		// the server ended the stream, the values it sent first and why it ended are left to be received
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// open opens the stream of values from the generator, resuming from the last token received.
+// The doubler and random generators are served by their own rpcs, any other is requested by name.
+func (s *Stream) open(ctx context.Context) (clientStream, error) {
+	if s.clientID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "client-id", s.clientID)
+	}
+
+	req := &v1.Request{
+		Qty:        s.state.Quantity(),
+		Seed:       s.req.Seed,
+		Token:      s.token,
+		Last:       s.last,
+		IntervalMs: uint32(s.req.Interval.Milliseconds()),
+		Params:     s.req.Params,
+		Integrity:  s.req.Integrity,
+	}
+
+	service := s.client.transport.service()
+	switch {
+	case s.req.Subscribe:
+		return s.subscribe(ctx, service, req)
+	case s.req.Generator == random.Name:
+		return service.Random(ctx, req, grpcRetry.WithMax(MaxRetries))
+	case s.req.Generator == doubler.Name:
+		return service.Doubler(ctx, req, grpcRetry.WithMax(MaxRetries))
+	default:
+		return service.Generate(ctx, &v1.GenerateRequest{Generator: s.req.Generator, Request: req}, grpcRetry.WithMax(MaxRetries))
+	}
+}
+
+// receive processes the stream of values maintaining the state of those received, returning once the server
+// finishes the stream whether the total matched its checksum. Values are de-duplicated using their sequence position
+// so a resumed stream never double counts.
+func (s *Stream) receive(reconnected bool) (bool, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	stream, err := s.open(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	logger := s.client.opts.logger
+	chained := s.req.Integrity == v1.Integrity_INTEGRITY_CHAIN
+	checksum := new(big.Int)
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return s.state.Total().Cmp(checksum) == 0, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if reconnected {
+			reconnected = false
+			if !s.emit(Event{Type: Resumed, Sequence: s.state.Position()}) {
+				return false, fatal{s.ctx.Err()}
+			}
+		}
+
+		// the server is shutting down, resume from the last value received once reconnected
+		if response.GoingAway {
+			return false, ErrGoingAway
+		}
+
+		// only values carry a resume token, the final response carries the checksum
+		if response.Token == nil {
+			checksum.SetBytes(response.Checksum)
+			if chained && !bytes.Equal(s.state.Chain(), response.Chain) {
+				return false, diverged(s.state.Position(), "chain head differs")
+			}
+			event := Event{Type: Checksum, Total: s.state.Total(), Checksum: new(big.Int).Set(checksum)}
+			if chained {
+				event.Chain = response.Chain
+			}
+			if !s.emit(event) {
+				return false, fatal{s.ctx.Err()}
+			}
+
+			continue
+		}
+
+		received := s.state.Position()
+		if response.Sequence < received {
+			logger.Debug().Int64("sequence", response.Sequence).Msg("Discarding duplicate value")
+			if err := acknowledge(stream, received-1); err != nil {
+				return false, err
+			}
+
+			continue
+		}
+		if response.Sequence > received {
+			return false, fmt.Errorf("%w: expected %d received %d", ErrSequenceGap, received, response.Sequence)
+		}
+
+		// the first value of a resumed stream carries the total of those before it, which the tally must match
+		if response.Total != nil {
+			if total := new(big.Int).SetBytes(response.Total); s.state.Total().Cmp(total) != 0 {
+				return false, diverged(response.Sequence, fmt.Sprintf("tally %s differs from total %s", s.state.Total(), total))
+			}
+		}
+
+		value := new(big.Int).SetBytes(response.Value)
+		s.record(value, response.Token)
+		// the first value whose chain differs is where the values received diverged from those sent
+		if chained && !bytes.Equal(s.state.Chain(), response.Chain) {
+			return false, diverged(response.Sequence, "chain differs")
+		}
+		// the value is only acknowledged once journalled so the server resends it should the client die first
+		if s.journal != nil {
+			if err := s.journal.append(entry{Sequence: response.Sequence, Value: response.Value, Token: response.Token}); err != nil {
+				return false, fatal{err}
+			}
+		}
+
+		event := Event{Type: Value, Sequence: response.Sequence, Value: value, Total: s.state.Total()}
+		if chained {
+			event.Chain = s.state.Chain()
+		}
+		if !s.emit(event) {
+			return false, fatal{s.ctx.Err()}
+		}
+		if err := acknowledge(stream, response.Sequence); err != nil {
+			return false, err
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	grpcRetry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// transport carries the rpcs of the service to the server.
+type transport interface {
+	// service returns the rpcs of the sequence service.
+	service() v1.ServiceClient
+	// health returns the standard health service of the server.
+	health() (healthpb.HealthClient, error)
+	// reconnect waits for the server to become reachable after a stream was lost, until the context is done.
+	reconnect(ctx context.Context) error
+	close() error
+}
+
+// bearerToken attaches a bearer token to every rpc, read afresh from the file for each so rotated tokens are picked up.
+type bearerToken struct {
+	token string
+	file  string
+}
+
+// GetRequestMetadata returns the authorization metadata carrying the token, implementing credentials.PerRPCCredentials.
+func (b bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	token := b.token
+	if b.file != "" {
+		data, err := os.ReadFile(b.file)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return nil, ErrToken
+	}
+
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity allows tokens to be sent without TLS so that local servers may be used,
+// in production the connection must be secured as the token is otherwise sent in the clear.
+func (b bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// perRPCCredentials returns the credentials attached to every rpc, nil when none are configured.
+func (o *options) perRPCCredentials() (credentials.PerRPCCredentials, error) {
+	switch {
+	case o.token != "" && o.tokenFile != "":
+		return nil, fmt.Errorf("%w: supply only one of a token and token file", ErrToken)
+	case (o.token != "" || o.tokenFile != "") && o.credentials != nil:
+		return nil, fmt.Errorf("%w: supply only one of a token and credentials", ErrToken)
+	case o.token != "" || o.tokenFile != "":
+		return bearerToken{token: o.token, file: o.tokenFile}, nil
+	default:
+		return o.credentials, nil
+	}
+}
+
+// grpcTransport carries the rpcs over a grpc connection.
+type grpcTransport struct {
+	conn *grpc.ClientConn
+}
+
+// dialGRPC connects to the target over grpc, with some simple retry logic in the event of connection loss.
+func dialGRPC(target string, o *options) (*grpcTransport, error) {
+	creds := insecure.NewCredentials()
+	if o.tls != nil {
+		creds = credentials.NewTLS(o.tls)
+	}
+
+	perRPC, err := o.perRPCCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []grpc.DialOption
+	opts = append(opts, grpc.WithTransportCredentials(creds))
+	if perRPC != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(perRPC))
+	}
+	opts = append(opts, grpc.WithKeepaliveParams(kacp))
+
+	retryOpts := []grpcRetry.CallOption{
+		grpcRetry.WithBackoff(grpcRetry.BackoffExponentialWithJitter(100*time.Millisecond, 0.10)),
+		grpcRetry.WithCodes(codes.NotFound, codes.Aborted, codes.Canceled, codes.Unavailable, codes.Unknown),
+	}
+	opts = append(opts, grpc.WithStreamInterceptor(grpcRetry.StreamClientInterceptor(retryOpts...)))
+	opts = append(opts, o.dialOptions...)
+
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grpcTransport{conn: conn}, nil
+}
+
+func (t *grpcTransport) service() v1.ServiceClient {
+	return v1.NewServiceClient(t.conn)
+}
+
+func (t *grpcTransport) health() (healthpb.HealthClient, error) {
+	return healthpb.NewHealthClient(t.conn), nil
+}
+
+// reconnect waits for the connection to become ready, asking it to connect whenever it is idle or has failed.
+func (t *grpcTransport) reconnect(ctx context.Context) error {
+	state := t.conn.GetState()
+	for state != connectivity.Ready {
+		if state != connectivity.Connecting {
+			t.conn.Connect()
+		}
+		if !t.conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+		state = t.conn.GetState()
+	}
+
+	return nil
+}
+
+func (t *grpcTransport) close() error {
+	return t.conn.Close()
+}
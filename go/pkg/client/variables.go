@@ -0,0 +1,53 @@
+package client
+
+import (
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// DefaultReconnectTimeout is how long a lost stream keeps trying to reconnect for before giving up.
+	DefaultReconnectTimeout = time.Duration(300) * time.Second
+
+	// MaxRetries of opening a stream over grpc before the stream is reported as lost.
+	MaxRetries = 5
+
+	// WebSocketPath the server serves streams over a WebSocket at.
+	WebSocketPath = "/ws"
+
+	// HandshakeTimeout bounds how long opening a WebSocket may take.
+	HandshakeTimeout = time.Duration(10) * time.Second
+
+	// MinBackoff and MaxBackoff bound the wait between attempts to reach the server over a WebSocket,
+	// doubling with each attempt.
+	MinBackoff = time.Duration(100) * time.Millisecond
+	MaxBackoff = time.Duration(5) * time.Second
+)
+
+// Transport is how the client reaches the server.
+type Transport string
+
+const (
+	// GRPC streams over the rpcs of the service.
+	GRPC Transport = "grpc"
+	// WebSocket streams over a WebSocket to the server, it cannot subscribe nor check health or list generators.
+	WebSocket Transport = "ws"
+)
+
+// Framing is how messages are framed over a WebSocket.
+type Framing string
+
+const (
+	// Binary frames each message as protobuf.
+	Binary Framing = "binary"
+	// JSON frames each message as text, the protobuf JSON mapping of the message.
+	JSON Framing = "json"
+)
+
+// kacp configuration for keeping a connection alive
+var kacp = keepalive.ClientParameters{
+	Time:                1 * time.Second, // send pings every second if there is no activity
+	Timeout:             time.Second,     // wait 1 second for ping ack before considering the connection dead
+	PermitWithoutStream: true,            // send pings even without active streams
+}
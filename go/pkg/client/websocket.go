@@ -0,0 +1,276 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/doubler"
+	"exercise/internal/framing"
+	"exercise/internal/random"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+// wsTransport carries the streaming rpcs over WebSockets, a connection is opened for each stream.
+type wsTransport struct {
+	url         *url.URL
+	dialer      *websocket.Dialer
+	credentials credentials.PerRPCCredentials
+	messageType int
+	logger      zerolog.Logger
+}
+
+// wsStream is the client side of a stream over a WebSocket, receiving the responses framed by the server.
+type wsStream struct {
+	ctx      context.Context
+	conn     *websocket.Conn
+	finished error
+}
+
+func (s *wsStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *wsStream) Trailer() metadata.MD         { return nil }
+func (s *wsStream) CloseSend() error             { return nil }
+func (s *wsStream) Context() context.Context     { return s.ctx }
+func (s *wsStream) SendMsg(interface{}) error {
+	return status.Error(codes.Unimplemented, "only the request may be sent")
+}
+func (s *wsStream) RecvMsg(m interface{}) error {
+	res, ok := m.(*v1.Response)
+	if !ok {
+		return status.Errorf(codes.Internal, "unable to receive %T", m)
+	}
+
+	received, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	proto.Reset(res)
+	proto.Merge(res, received)
+
+	return nil
+}
+
+// Recv receives the next response, io.EOF once the server reports the stream finished successfully.
+// Losing the connection is reported as Unavailable so the stream is resumed as a lost rpc would be.
+func (s *wsStream) Recv() (*v1.Response, error) {
+	if s.finished != nil {
+		return nil, s.finished
+	}
+
+	messageType, data, err := s.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			err = status.Error(codes.Unavailable, "connection closed before the stream finished")
+		} else if s.ctx.Err() != nil {
+			err = status.FromContextError(s.ctx.Err()).Err()
+		} else {
+			err = status.Error(codes.Unavailable, err.Error())
+		}
+		s.finish(err)
+
+		return nil, err
+	}
+
+	// a status frame is the last, ending the stream with its error
+	res, err := framing.DecodeResponse(messageType, data)
+	if errors.Is(err, framing.ErrFrame) {
+		err = status.Error(codes.Internal, err.Error())
+	}
+	if err != nil {
+		s.finish(err)
+
+		return nil, err
+	}
+	if res == nil {
+		s.finish(io.EOF)
+
+		return nil, io.EOF
+	}
+
+	return res, nil
+}
+
+// finish closes the connection, subsequent receives return err.
+func (s *wsStream) finish(err error) {
+	s.finished = err
+	_ = s.conn.Close()
+}
+
+// code maps the HTTP status the server refused the WebSocket with to the closest status code.
+func code(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Unavailable
+	}
+}
+
+// open opens a WebSocket and sends the request, the client-id and credentials are sent as headers.
+func (t *wsTransport) open(ctx context.Context, req *v1.GenerateRequest) (*wsStream, error) {
+	header := http.Header{}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if ids := md.Get("client-id"); len(ids) > 0 {
+		header.Set("Client-Id", ids[0])
+	}
+	if t.credentials != nil {
+		authorization, err := t.credentials.GetRequestMetadata(ctx, t.url.String())
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		for key, value := range authorization {
+			header.Set(key, value)
+		}
+	}
+
+	conn, resp, err := t.dialer.DialContext(ctx, t.url.String(), header)
+	if err != nil {
+		if resp != nil {
+			return nil, status.Errorf(code(resp.StatusCode), "websocket refused: %s", resp.Status)
+		}
+
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	data, err := framing.EncodeRequest(t.messageType, req)
+	if err != nil {
+		_ = conn.Close()
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := conn.WriteMessage(t.messageType, data); err != nil {
+		_ = conn.Close()
+
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	// abandoning the stream closes the connection, unblocking any receive
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	return &wsStream{ctx: ctx, conn: conn}, nil
+}
+
+// wsService streams the sequences over WebSockets, implementing the rpcs the client uses.
+type wsService struct {
+	transport *wsTransport
+}
+
+func (s wsService) Doubler(ctx context.Context, in *v1.Request, _ ...grpc.CallOption) (v1.Service_DoublerClient, error) {
+	return s.transport.open(ctx, &v1.GenerateRequest{Generator: doubler.Name, Request: in})
+}
+
+func (s wsService) Random(ctx context.Context, in *v1.Request, _ ...grpc.CallOption) (v1.Service_RandomClient, error) {
+	return s.transport.open(ctx, &v1.GenerateRequest{Generator: random.Name, Request: in})
+}
+
+func (s wsService) Generate(ctx context.Context, in *v1.GenerateRequest, _ ...grpc.CallOption) (v1.Service_GenerateClient, error) {
+	return s.transport.open(ctx, in)
+}
+
+func (s wsService) Subscribe(context.Context, ...grpc.CallOption) (v1.Service_SubscribeClient, error) {
+	return nil, fmt.Errorf("subscribe is %w", ErrUnsupported)
+}
+
+func (s wsService) ListGenerators(context.Context, *v1.ListGeneratorsRequest, ...grpc.CallOption) (*v1.ListGeneratorsResponse, error) {
+	return nil, fmt.Errorf("listing generators is %w", ErrUnsupported)
+}
+
+func (t *wsTransport) service() v1.ServiceClient {
+	return wsService{transport: t}
+}
+
+func (t *wsTransport) health() (healthpb.HealthClient, error) {
+	return nil, fmt.Errorf("health checking is %w", ErrUnsupported)
+}
+
+// reconnect waits for the server to become reachable again, backing off exponentially between attempts.
+func (t *wsTransport) reconnect(ctx context.Context) error {
+	for backoff := MinBackoff; ; backoff *= 2 {
+		if backoff > MaxBackoff {
+			backoff = MaxBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		conn, err := t.dialer.NetDialContext(ctx, "tcp", t.url.Host)
+		if err == nil {
+			_ = conn.Close()
+
+			return nil
+		}
+		t.logger.Debug().Err(err).Dur("backoff", backoff).Msg("Server unreachable")
+	}
+}
+
+// close has nothing to close, each stream closes its own connection.
+func (t *wsTransport) close() error {
+	return nil
+}
+
+// dialWebSocket prepares to stream from the server at the target, its WebSocketPath being served over TLS
+// when configured.
+func dialWebSocket(target string, o *options) (*wsTransport, error) {
+	perRPC, err := o.perRPCCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	messageType := websocket.BinaryMessage
+	switch o.framing {
+	case Binary:
+	case JSON:
+		messageType = websocket.TextMessage
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrFraming, o.framing)
+	}
+
+	endpoint := &url.URL{Scheme: "ws", Host: target, Path: WebSocketPath}
+	if o.tls != nil {
+		endpoint.Scheme = "wss"
+	}
+
+	dialer := &net.Dialer{Timeout: HandshakeTimeout}
+
+	return &wsTransport{
+		url: endpoint,
+		dialer: &websocket.Dialer{
+			NetDialContext:   dialer.DialContext,
+			TLSClientConfig:  o.tls,
+			HandshakeTimeout: HandshakeTimeout,
+			Proxy:            http.ProxyFromEnvironment,
+		},
+		credentials: perRPC,
+		messageType: messageType,
+		logger:      o.logger,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/rs/cors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+// options configure a Server, set by each Option.
+type options struct {
+	address      string
+	listener     net.Listener
+	httpAddress  string
+	tls          *tls.Config
+	store        Store
+	stateFile    string
+	stateTTL     time.Duration
+	generators   []Generator
+	interceptors []grpc.StreamServerInterceptor
+	unary        []grpc.UnaryServerInterceptor
+	pacing       Pacing
+	quotas       Quotas
+	observer     Observer
+	cors         *cors.Cors
+	reflection   bool
+	logger       zerolog.Logger
+}
+
+// Option configures a Server.
+type Option func(*options)
+
+// WithAddress sets the address the server listens on, DefaultAddress by default.
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// WithListener serves on the listener in place of listening on an address, it is closed once the server is shut down.
+func WithListener(l net.Listener) Option {
+	return func(o *options) {
+		o.listener = l
+	}
+}
+
+// WithHTTPAddress additionally serves the HTTP gateway on the address, streaming GET /v1/{generator} as Server-Sent
+// Events and the rpcs over a WebSocket at /ws. gRPC-Web and Connect are always served alongside grpc.
+func WithHTTPAddress(address string) Option {
+	return func(o *options) {
+		o.httpAddress = address
+	}
+}
+
+// WithTLS serves over TLS as configured, which may require clients to present certificates.
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tls = config
+	}
+}
+
+// WithStore sets the store client states are retained in, taking precedence over WithStateFile and WithStateTTL.
+// The server closes the store once shut down, it is left open should the server fail to start.
+func WithStore(st Store) Option {
+	return func(o *options) {
+		o.store = st
+	}
+}
+
+// WithStateFile persists client states to the file so they survive a restart, held in memory by default.
+func WithStateFile(path string) Option {
+	return func(o *options) {
+		o.stateFile = path
+	}
+}
+
+// WithStateTTL sets how long client states are retained for after last being accessed, DefaultStateTTL by default.
+func WithStateTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.stateTTL = ttl
+	}
+}
+
+// WithGenerators sets the generators the server can stream, every built in generator by default.
+func WithGenerators(generators ...Generator) Option {
+	return func(o *options) {
+		o.generators = append(o.generators, generators...)
+	}
+}
+
+// WithStreamInterceptors adds interceptors run, in order, before every stream whether over grpc, gRPC-Web, Connect
+// or the HTTP gateway.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithUnaryInterceptors adds interceptors run, in order, before every unary rpc.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) {
+		o.unary = append(o.unary, interceptors...)
+	}
+}
+
+// WithPacing sets the policy determining how values are spaced out on each stream, DefaultPacing by default.
+func WithPacing(policy Pacing) Option {
+	return func(o *options) {
+		o.pacing = policy
+	}
+}
+
+// WithQuotas sets the policy bounding the resources clients may use, DefaultQuotas by default.
+func WithQuotas(policy Quotas) Option {
+	return func(o *options) {
+		o.quotas = policy
+	}
+}
+
+// WithObserver sets the observer notified of events within the service.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observer = observer
+	}
+}
+
+// WithCORS allows browsers on other origins to call gRPC-Web, Connect and the HTTP gateway as the policy permits.
+func WithCORS(policy *cors.Cors) Option {
+	return func(o *options) {
+		o.cors = policy
+	}
+}
+
+// WithReflection registers the grpc reflection service so tools such as grpcurl can introspect the server.
+func WithReflection() Option {
+	return func(o *options) {
+		o.reflection = true
+	}
+}
+
+// WithLogger sets the logger the server, the service and its gateways report to, nothing is logged by default.
+// Interceptors supplied by WithStreamInterceptors and WithUnaryInterceptors log as they are configured to.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
@@ -0,0 +1,306 @@
+// Package server embeds the ably exercise sequence service, serving grpc, gRPC-Web and Connect on a single listener
+// and optionally the HTTP gateway on another address:
+//
+//	srv, err := server.New(server.WithAddress("localhost:0"), server.WithPacing(server.Pacing{Mode: server.Burst}))
+//	if err != nil {
+//		return err
+//	}
+//	if err := srv.Start(); err != nil {
+//		return err
+//	}
+//	defer srv.Shutdown(context.Background())
+//
+//	conn, err := grpc.Dial(srv.Addr().String(), ...)
+package server
+
+import (
+	"context"
+	"errors"
+	"exercise/internal/gateway"
+	"exercise/internal/generator"
+	"exercise/internal/mux"
+	"exercise/internal/service"
+	"exercise/internal/store"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	v1 "exercise/pkg/ably/v1"
+)
+
+var (
+	// ErrStarted the server has already been started.
+	ErrStarted = errors.New("server already started")
+	// ErrNotStarted the server has not been started.
+	ErrNotStarted = errors.New("server not started")
+)
+
+// Server serves the sequence service, it is started once and shut down once.
+type Server struct {
+	opts     options
+	registry *generator.Registry
+
+	mu      sync.Mutex
+	started bool
+	svc     *service.Service
+	grpc    *grpc.Server
+	health  *health.Server
+	mux     *mux.Mux
+	// cancel stops maintaining states and the gateways.
+	cancel context.CancelFunc
+	// served receives why the grpc server stopped serving.
+	served         chan error
+	gatewayStopped chan struct{}
+	webStopped     chan struct{}
+
+	shutdown    sync.Once
+	shutdownErr error
+}
+
+// NewStore creates the store client states are retained in for ttl after last being accessed,
+// held on disk when a path is supplied otherwise in memory.
+func NewStore(path string, ttl time.Duration) (Store, error) {
+	if path == "" {
+		return store.NewMemory(ttl), nil
+	}
+
+	return store.NewFile(path, ttl)
+}
+
+// New configures a server, it does not listen until started.
+func New(opts ...Option) (*Server, error) {
+	o := options{
+		address:  DefaultAddress,
+		stateTTL: DefaultStateTTL,
+		pacing:   DefaultPacing,
+		quotas:   DefaultQuotas,
+		logger:   zerolog.Nop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := o.pacing.Validate(); err != nil {
+		return nil, err
+	}
+	if err := o.quotas.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{opts: o, registry: service.DefaultRegistry()}
+	if len(o.generators) > 0 {
+		registry, err := generator.NewRegistry(o.generators...)
+		if err != nil {
+			return nil, err
+		}
+		s.registry = registry
+	}
+
+	return s, nil
+}
+
+// Start listens and serves in the background until the server is shut down.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return ErrStarted
+	}
+
+	st := s.opts.store
+	if st == nil {
+		var err error
+		if st, err = NewStore(s.opts.stateFile, s.opts.stateTTL); err != nil {
+			return fmt.Errorf("unable to open state store: %w", err)
+		}
+	}
+
+	listener := s.opts.listener
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen("tcp", s.opts.address); err != nil {
+			// a store supplied by the caller remains theirs until the server has started
+			if s.opts.store == nil {
+				_ = st.Close()
+			}
+
+			return err
+		}
+	}
+
+	svcOpts := []service.Option{
+		service.WithStore(st),
+		service.WithPacing(s.opts.pacing),
+		service.WithQuotas(s.opts.quotas),
+		service.WithRegistry(s.registry),
+		service.WithLogger(s.opts.logger),
+	}
+	if s.opts.observer != nil {
+		svcOpts = append(svcOpts, service.WithObserver(s.opts.observer))
+	}
+	s.svc = service.NewService(svcOpts...)
+
+	// the service's own interceptor runs last so that any authentication has already named the client
+	interceptors := append(append([]grpc.StreamServerInterceptor{}, s.opts.interceptors...), s.svc.StreamServerInterceptor())
+
+	// gRPC-Web and Connect are served on the same listener as gRPC, the protocol of each connection being sniffed
+	s.mux = mux.NewMux(listener, s.opts.tls, mux.WithLogger(s.opts.logger))
+	s.health = health.NewServer()
+	s.grpc = s.buildServer(interceptors)
+	gw := gateway.NewGateway(s.svc,
+		gateway.WithStreamInterceptors(interceptors...),
+		gateway.WithUnaryInterceptors(s.opts.unary...),
+		gateway.WithCORS(s.opts.cors),
+		gateway.WithLogger(s.opts.logger),
+	)
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go s.svc.MaintainStates(ctx)
+
+	// the gateway streams through the same interceptors as the rpcs
+	s.gatewayStopped = make(chan struct{})
+	if s.opts.httpAddress != "" {
+		go func() {
+			defer close(s.gatewayStopped)
+			s.opts.logger.Info().Msgf("Serving HTTP gateway on %s%s{generator}", s.opts.httpAddress, gateway.Prefix)
+			if err := gw.Serve(ctx, s.opts.httpAddress, s.opts.tls); err != nil {
+				s.opts.logger.Error().Err(err).Msg("Unable to serve HTTP gateway")
+			}
+		}()
+	} else {
+		close(s.gatewayStopped)
+	}
+
+	s.webStopped = make(chan struct{})
+	go func() {
+		defer close(s.webStopped)
+		if err := gw.ServeListener(ctx, s.mux.HTTP()); err != nil {
+			s.opts.logger.Error().Err(err).Msg("Unable to serve gRPC-Web and Connect")
+		}
+	}()
+	go func() {
+		if err := s.mux.Serve(); err != nil {
+			s.opts.logger.Error().Err(err).Msg("Unable to accept connections")
+		}
+	}()
+
+	s.served = make(chan error, 1)
+	go func() { s.served <- s.grpc.Serve(s.mux.GRPC()) }()
+	s.started = true
+
+	return nil
+}
+
+// buildServer creates the grpc server, registering the standard health service
+// and, if enabled, the reflection service alongside the sequence service.
+func (s *Server) buildServer(interceptors []grpc.StreamServerInterceptor) *grpc.Server {
+	var opts []grpc.ServerOption
+	opts = append(opts, grpc.Creds(s.mux.Credentials()))
+	opts = append(opts, grpc.ChainStreamInterceptor(interceptors...))
+	opts = append(opts, grpc.ChainUnaryInterceptor(s.opts.unary...))
+	srv := grpc.NewServer(opts...)
+
+	v1.RegisterServiceServer(srv, s.svc)
+	healthpb.RegisterHealthServer(srv, s.health)
+	s.health.SetServingStatus(v1.Service_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	if s.opts.reflection {
+		reflection.Register(srv)
+	}
+
+	return srv
+}
+
+// Addr returns the address the server is listening on, nil until started.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mux == nil {
+		return nil
+	}
+
+	return s.mux.GRPC().Addr()
+}
+
+// Wait blocks until the server stops serving, returning why should it stop other than by being shut down.
+func (s *Server) Wait() error {
+	s.mu.Lock()
+	served := s.served
+	s.mu.Unlock()
+
+	if served == nil {
+		return ErrNotStarted
+	}
+
+	err := <-served
+	served <- err
+
+	return err
+}
+
+// Shutdown gracefully stops the server, health checks report NOT_SERVING while active streams are given until ctx
+// is done to complete before being drained with a going away response so that clients resume elsewhere.
+// The state store is closed once every stream has finished.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+
+	if !started {
+		return ErrNotStarted
+	}
+
+	s.shutdown.Do(func() {
+		s.stop(ctx)
+
+		// gateway, gRPC-Web and Connect streams are not tracked by the grpc server so any remaining are drained
+		// before it is stopped
+		s.svc.Drain()
+		_ = s.mux.Close()
+		s.cancel()
+		<-s.gatewayStopped
+		<-s.webStopped
+		if err := s.svc.Close(); err != nil {
+			s.shutdownErr = fmt.Errorf("unable to close state store: %w", err)
+		}
+	})
+
+	return s.shutdownErr
+}
+
+// stop gracefully stops the grpc server, draining any streams remaining once ctx is done
+// and forcing them to stop should they fail to drain.
+func (s *Server) stop(ctx context.Context) {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return
+	case <-ctx.Done():
+		s.opts.logger.Warn().Msg("Grace period expired: draining active streams")
+		s.svc.Drain()
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(DrainTimeout):
+		s.opts.logger.Warn().Msg("Streams failed to drain: forcing stop")
+		s.grpc.Stop()
+	}
+}
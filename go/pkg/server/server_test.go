@@ -0,0 +1,341 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+
+	v1 "exercise/pkg/ably/v1"
+	"exercise/pkg/server"
+)
+
+// squares is a generator implemented outside the module producing the squares from the seed.
+type squares struct{}
+
+func (squares) Name() string                         { return "squares" }
+func (squares) Description() string                  { return "the squares from the seed" }
+func (squares) Deterministic(map[string]string) bool { return true }
+
+func (squares) Params() []server.Param {
+	return []server.Param{{Name: "step", Description: "added to the root of each value", Default: "1"}}
+}
+
+func (squares) Iterator(seed *big.Int, params map[string]string) (server.Iterator, error) {
+	step, err := strconv.ParseInt(params["step"], 10, 64)
+	if err != nil || step < 1 {
+		return nil, &server.ParamError{Name: "step", Reason: "must be a positive integer"}
+	}
+
+	return &squaresIterator{seed: seed.Int64(), step: step, root: seed.Int64()}, nil
+}
+
+type squaresIterator struct {
+	seed, step, root int64
+}
+
+func (it *squaresIterator) Next() *big.Int {
+	value := big.NewInt(it.root * it.root)
+	it.root += it.step
+
+	return value
+}
+
+func (it *squaresIterator) Reset() { it.root = it.seed }
+
+func (it *squaresIterator) SeekTo(position int64) { it.root = it.seed + position*it.step }
+
+var _ server.RandomAccess = (*squaresIterator)(nil)
+
+// encodedStore is a store implemented outside the module retaining each state encoded.
+type encodedStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+func (s *encodedStore) Load(clientID string) (*server.State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.states[clientID]
+	if !ok {
+		return nil, false
+	}
+	st := new(server.State)
+	if err := st.UnmarshalBinary(data); err != nil {
+		return nil, false
+	}
+
+	return st, true
+}
+
+func (s *encodedStore) Save(clientID string, st *server.State) error {
+	data, err := st.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[clientID] = data
+
+	return nil
+}
+
+func (s *encodedStore) Delete(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, clientID)
+
+	return nil
+}
+
+func (s *encodedStore) Expire() ([]string, error) { return nil, nil }
+
+func (s *encodedStore) Range(fn func(clientID string, st *server.State) bool) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.states))
+	for id := range s.states {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		if st, ok := s.Load(id); ok && !fn(id, st) {
+			return
+		}
+	}
+}
+
+func (s *encodedStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.states)
+}
+
+func (s *encodedStore) Flush() error { return nil }
+func (s *encodedStore) Close() error { return nil }
+
+var _ server.Store = (*encodedStore)(nil)
+
+func TestExternalGeneratorAndStore(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &encodedStore{states: map[string][]byte{}}
+	srv, err := server.New(
+		server.WithListener(lis),
+		server.WithStore(st),
+		server.WithGenerators(squares{}),
+		server.WithPacing(server.Pacing{Mode: server.Burst}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+
+	conn, err := grpc.Dial(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := v1.NewServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "client-id", "external")
+
+	stream, err := client.Generate(ctx, &v1.GenerateRequest{
+		Generator: "squares",
+		Request:   &v1.Request{Qty: 4, Seed: 2, Params: map[string]string{"step": "3"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []int64
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Token != nil {
+			values = append(values, new(big.Int).SetBytes(res.Value).Int64())
+		}
+	}
+
+	want := []int64{4, 25, 64, 121}
+	if len(values) != len(want) {
+		t.Fatalf("received %v want %v", values, want)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Fatalf("received %v want %v", values, want)
+		}
+	}
+	if st.Len() != 1 {
+		t.Fatalf("store holds %d states", st.Len())
+	}
+
+	stream, err = client.Generate(ctx, &v1.GenerateRequest{
+		Generator: "squares",
+		Request:   &v1.Request{Qty: 4, Seed: 2, Params: map[string]string{"step": "0"}},
+	})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid step expected %s, got %v", codes.InvalidArgument, err)
+	}
+}
+
+// closingStore records whether it was closed.
+type closingStore struct {
+	*encodedStore
+	closed bool
+}
+
+func (s *closingStore) Close() error {
+	s.closed = true
+
+	return nil
+}
+
+func TestStartFailureLeavesStoreOpen(t *testing.T) {
+	t.Parallel()
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	st := &closingStore{encodedStore: &encodedStore{states: map[string][]byte{}}}
+	srv, err := server.New(server.WithAddress(taken.Addr().String()), server.WithStore(st))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err == nil {
+		t.Fatal("started on an address already in use")
+	}
+	if st.closed {
+		t.Fatal("store supplied by the caller closed as the server failed to start")
+	}
+}
+
+// listServices asks the reflection service for the services the server registered.
+func listServices(conn *grpc.ClientConn) ([]string, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = stream.CloseSend() }()
+
+	req := &reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, service := range res.GetListServicesResponse().GetService() {
+		names = append(names, service.Name)
+	}
+
+	return names, nil
+}
+
+func TestHealthAndReflection(t *testing.T) {
+	t.Parallel()
+
+	for _, reflected := range []bool{false, true} {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := []server.Option{server.WithListener(lis)}
+		if reflected {
+			opts = append(opts, server.WithReflection())
+		}
+		srv, err := server.New(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatal(err)
+		}
+		conn, err := grpc.Dial(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		// the server as a whole and the sequence service report serving
+		health := healthpb.NewHealthClient(conn)
+		for _, service := range []string{"", v1.Service_ServiceDesc.ServiceName} {
+			res, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+			if err != nil || res.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Fatalf("service %q reported %s: %v", service, res.GetStatus(), err)
+			}
+		}
+
+		// reflection is only served when enabled, listing every service registered
+		names, err := listServices(conn)
+		switch {
+		case !reflected && status.Code(err) != codes.Unimplemented:
+			t.Fatalf("expected reflection to be %s, got %v", codes.Unimplemented, err)
+		case reflected && err != nil:
+			t.Fatal(err)
+		case reflected && !strings.Contains(strings.Join(names, ","), v1.Service_ServiceDesc.ServiceName):
+			t.Fatalf("reflection listed %v", names)
+		}
+
+		// shutting down is reported to health checks watching the server before it stops
+		watch, err := health.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res, err := watch.Recv(); err != nil || res.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("watch reported %s: %v", res.GetStatus(), err)
+		}
+		shutdown := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			shutdown <- srv.Shutdown(ctx)
+		}()
+		if res, err := watch.Recv(); err != nil || res.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Fatalf("watch reported %s once shutting down: %v", res.GetStatus(), err)
+		}
+		_ = conn.Close()
+		if err := <-shutdown; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"exercise/internal/generator"
+	"exercise/internal/pacing"
+	"exercise/internal/quota"
+	"exercise/internal/service"
+	"exercise/internal/state"
+	"exercise/internal/store"
+	"time"
+)
+
+const (
+	// DefaultAddress the server listens on when no address or listener is supplied.
+	DefaultAddress = ":9090"
+
+	// DefaultStateTTL is how long client states are retained for after last being accessed.
+	DefaultStateTTL = time.Duration(service.StateTTL) * time.Second
+
+	// DrainTimeout is how long drained streams are given to send their going away response before the server is stopped.
+	DrainTimeout = time.Duration(5) * time.Second
+
+	// SeedParam names the seed in a ParamError, the seed being validated alongside the parameters.
+	SeedParam = generator.Seed
+)
+
+// Store retains the state of client sequences between requests so streams may be resumed.
+type Store = store.Store
+
+// State is the progress of a client's sequence retained by a Store. A store may persist it with MarshalBinary,
+// restoring it into a new(State) with UnmarshalBinary, and evict it once Accessed exceeds the TTL.
+type State = state.State
+
+// Generator produces the values of a sequence the server can stream.
+type Generator = generator.Generator
+
+// Iterator produces the values of a sequence on demand, returned by a Generator.
+type Iterator = generator.Iterator
+
+// RandomAccess is implemented by iterators able to move directly to a position in the sequence.
+type RandomAccess = generator.RandomAccess
+
+// Param describes a parameter accepted by a Generator.
+type Param = generator.Param
+
+// ParamError describes a parameter a Generator cannot accept, reported to the client as an invalid argument.
+type ParamError = generator.ParamError
+
+// Pacing is the policy determining how values are spaced out on each stream.
+type Pacing = pacing.Policy
+
+// Quotas is the policy bounding the resources each client, and the server as a whole, may use.
+type Quotas = quota.Policy
+
+// Observer is notified of events within the service, such as each value sent.
+type Observer = service.Observer
+
+// Pacing modes, see Pacing.
+const (
+	Fixed  = pacing.Fixed
+	Burst  = pacing.Burst
+	Rate   = pacing.Rate
+	Jitter = pacing.Jitter
+)
+
+// DefaultPacing waits a second between values, requests may ask for any interval.
+var DefaultPacing = pacing.DefaultPolicy
+
+// DefaultQuotas leaves every resource unbounded.
+var DefaultQuotas = quota.DefaultPolicy